@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// configCommand handles `agent config show`, which prints the effective
+// configuration for the given flags and where each value came from.
+func configCommand(args []string) {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Println("Usage: agent config show [--config <file>] [flags]")
+		os.Exit(1)
+	}
+
+	cfg, fs := loadConfig("config show", args[1:])
+	fs.Parse(args[1:])
+	resolveConfig(cfg)
+
+	if path := fs.Lookup("config").Value.String(); path != "" {
+		fmt.Printf("⚙️  Effective configuration (config file: %s)\n\n", path)
+	} else {
+		fmt.Printf("⚙️  Effective configuration (no config file)\n\n")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, f := range cfg.Fields() {
+		value := f.Value
+		if value == "" {
+			value = `""`
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f.Key, value, f.Source)
+	}
+	w.Flush()
+
+	fmt.Println("\nPrecedence: flags > AGENT_* environment > config file > defaults")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"browser-agent/internal/e2e"
+)
+
+// e2eCommand runs the end-to-end suite against the offline mock storefront.
+func e2eCommand(args []string) {
+	cfg := e2e.NewConfig()
+
+	fs := flag.NewFlagSet("e2e", flag.ExitOnError)
+	headed := fs.Bool("headed", false, "show the browser window")
+	filter := fs.String("run", "", "only run scenarios whose name contains this string")
+	fs.Parse(args)
+
+	cfg.Headless = !*headed
+
+	var scenarios []e2e.Scenario
+	for _, sc := range e2e.Scenarios() {
+		if strings.Contains(sc.Name, *filter) {
+			scenarios = append(scenarios, sc)
+		}
+	}
+	if len(scenarios) == 0 {
+		fmt.Printf("No e2e scenarios match %q\n", *filter)
+		os.Exit(1)
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	results := e2e.Run(ctx, cfg, scenarios)
+	if len(results) < len(scenarios) {
+		fmt.Printf("Interrupted after %d of %d scenarios\n", len(results), len(scenarios))
+	}
+
+	failed := len(scenarios) - len(results)
+	for _, res := range results {
+		if res.Passed {
+			fmt.Printf("--- PASS: %s (%d steps, %v)\n", res.Scenario, res.StepsExecuted, res.Duration.Round(time.Millisecond))
+			continue
+		}
+		failed++
+		fmt.Printf("--- FAIL: %s (%v)\n    %v\n", res.Scenario, res.Duration.Round(time.Millisecond), res.Err)
+	}
+
+	if failed > 0 {
+		fmt.Printf("FAIL: %d of %d scenarios failed\n", failed, len(scenarios))
+		os.Exit(1)
+	}
+	fmt.Printf("PASS: %d scenarios\n", len(scenarios))
+}
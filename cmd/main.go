@@ -1,104 +1,336 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"browser-agent/internal/amazon_agent"
 	"browser-agent/internal/config"
+	"browser-agent/internal/llm"
 )
 
 func main() {
-	if len(os.Args) < 3 {
+	if len(os.Args) < 2 {
 		printUsage()
 		os.Exit(1)
 	}
 
 	command := os.Args[1]
-	if command != "run" {
+	switch command {
+	case "run":
+		runCommand(os.Args[2:])
+	case "resume":
+		resumeCommand(os.Args[2:])
+	case "report":
+		reportCommand(os.Args[2:])
+	case "config":
+		configCommand(os.Args[2:])
+	case "e2e":
+		e2eCommand(os.Args[2:])
+	default:
 		fmt.Printf("Unknown command: %s\n", command)
-		fmt.Println("Only 'run' command is supported")
+		fmt.Println("Supported commands: run, resume, report, config, e2e")
 		os.Exit(1)
 	}
+}
+
+func runCommand(args []string) {
+	cfg, fs := loadConfig("run", args)
+	fs.Parse(args)
+	resolveConfig(cfg)
 
-	taskDescription := strings.Join(os.Args[2:], " ")
+	taskDescription := strings.Join(fs.Args(), " ")
 	if taskDescription == "" {
 		fmt.Println("Error: Task description cannot be empty")
 		os.Exit(1)
 	}
+	progress, err := progressOutput(cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	observer, err := newObserver(cfg.LogFormat, progress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 
-	cfg := config.NewConfig()
-
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		fmt.Println("Error: GEMINI_API_KEY environment variable not set")
-		fmt.Println("This should be your OpenRouter API key")
-		fmt.Println("Get your API key at: https://openrouter.ai/keys")
+	llmClient, err := newLLMClient(cfg)
+	if err != nil {
+		fmt.Printf("Error initializing LLM client: %v\n", err)
 		os.Exit(1)
 	}
 
-	agent, err := amazon_agent.NewAgent(cfg, apiKey)
+	agent, err := amazon_agent.NewAgent(cfg, llmClient)
 	if err != nil {
 		fmt.Printf("Error initializing agent: %v\n", err)
 		os.Exit(1)
 	}
 	defer agent.Close()
+	agent.SetObserver(observer)
+
+	if cfg.LogFormat == "text" {
+		fmt.Fprintf(progress, "\n🤖 Advanced Browser Agent Starting...\n")
+		fmt.Fprintf(progress, "📋 Task: %s\n\n", taskDescription)
+		fmt.Fprintf(progress, "⚙️  Configuration:\n")
+		fmt.Fprintf(progress, "   Max Steps: %d\n", cfg.MaxSteps)
+		fmt.Fprintf(progress, "   Total Timeout: %v\n", cfg.TotalTimeout)
+		fmt.Fprintf(progress, "   Headless: %v\n", cfg.Headless)
+		if cfg.Trace || cfg.Video {
+			fmt.Fprintf(progress, "   Recording: trace=%v video=%v\n", cfg.Trace, cfg.Video)
+		}
+		fmt.Fprintf(progress, "   LLM Provider: %s\n", cfg.LLMProvider)
+		if cfg.Session != "" {
+			fmt.Fprintf(progress, "   Session: %s\n", cfg.Session)
+		}
+		if cfg.EnableRecovery {
+			fmt.Fprintf(progress, "   Recovery: %s\n\n", strings.Join(cfg.RecoveryStrategies, ", "))
+		} else {
+			fmt.Fprintf(progress, "   Recovery: off\n\n")
+		}
+
+		fmt.Fprint(progress, "🚀 Starting execution...\n\n")
+	}
 
-	fmt.Printf("\n🤖 Advanced Browser Agent Starting...\n")
-	fmt.Printf("📋 Task: %s\n\n", taskDescription)
-	fmt.Printf("⚙️  Configuration:\n")
-	fmt.Printf("   Max Steps: %d\n", cfg.MaxSteps)
-	fmt.Printf("   Total Timeout: %v\n", cfg.TotalTimeout)
-	fmt.Printf("   Headless: %v\n", cfg.Headless)
-	fmt.Printf("   Recovery: %v\n\n", cfg.EnableRecovery)
+	ctx, stop := signalContext()
+	defer stop()
 
-	fmt.Println("🚀 Starting execution...\n")
+	result, err := agent.ExecuteTask(ctx, taskDescription)
+	finish(cfg, agent, result, err)
+}
 
-	result, err := agent.ExecuteTask(taskDescription)
+// loadConfig layers the config file and environment over the defaults and
+// returns a flag set for command with a flag for every config key, plus
+// --config, so flags take precedence once parsed.
+func loadConfig(command string, args []string) (*config.Config, *flag.FlagSet) {
+	path := config.FilePath(args)
+	cfg, err := config.Load(path)
 	if err != nil {
-		fmt.Printf("\n❌ Task failed: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.String("config", path, "config file (YAML or TOML); default agent.yaml, agent.yml or agent.toml if present")
+	cfg.RegisterFlags(fs)
+	return cfg, fs
+}
+
+// resolveConfig fills in values implied by others: a replay fixture
+// selects the replay provider.
+func resolveConfig(cfg *config.Config) {
+	if cfg.LLMFixture != "" && cfg.LLMProvider != llm.ProviderReplay {
+		cfg.Set("llm_provider", llm.ProviderReplay, "implied by llm_fixture")
+	}
+}
+
+// progressOutput returns where progress is written for an --output value:
+// stdout, unless it is reserved for the task's JSON output.
+func progressOutput(cfg *config.Config) (io.Writer, error) {
+	switch cfg.Output {
+	case "text":
+		return os.Stdout, nil
+	case "json":
+		return os.Stderr, nil
+	default:
+		return nil, fmt.Errorf("unknown output %q (want text or json)", cfg.Output)
+	}
+}
+
+// newObserver returns the event observer for a --log-format value,
+// writing to w.
+func newObserver(format string, w io.Writer) (amazon_agent.Observer, error) {
+	switch format {
+	case "text":
+		return amazon_agent.NewConsoleRenderer(w), nil
+	case "json":
+		return amazon_agent.NewJSONLSink(w), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want text or json)", format)
+	}
+}
+
+// finish prints the run summary and exits with the matching status. With
+// JSON logs the task_done event is the summary, so only errors are printed,
+// to stderr. With --output json the summary and errors go to stderr, and
+// stdout gets the task's output alone.
+func finish(cfg *config.Config, agent *amazon_agent.Agent, result *amazon_agent.TaskResult, err error) {
+	w, _ := progressOutput(cfg)
+	if err != nil {
+		if cfg.LogFormat == "json" {
+			fmt.Fprintf(os.Stderr, "Task failed: %v\n", err)
+		} else {
+			fmt.Fprintf(w, "\n❌ Task failed: %v\n", err)
+		}
+		agent.Close()
 		os.Exit(1)
 	}
 
-	fmt.Printf("\n" + strings.Repeat("=", 60) + "\n")
+	if cfg.LogFormat == "text" {
+		printSummary(w, cfg, agent, result)
+	}
+
+	if result.Interrupted {
+		agent.Close()
+		os.Exit(130)
+	}
+	if cfg.Output == "json" {
+		if result.Output == nil {
+			fmt.Fprintf(os.Stderr, "Task produced no output\n")
+			agent.Close()
+			os.Exit(1)
+		}
+		fmt.Printf("%s\n", result.Output)
+	}
+}
+
+// printSummary writes the human-readable end of a run to w.
+func printSummary(w io.Writer, cfg *config.Config, agent *amazon_agent.Agent, result *amazon_agent.TaskResult) {
+	fmt.Fprint(w, "\n"+strings.Repeat("=", 60)+"\n")
 	if result.Success {
-		fmt.Printf("✅ Task completed successfully!\n")
+		fmt.Fprintf(w, "✅ Task completed successfully!\n")
+	} else if result.Interrupted {
+		fmt.Fprintf(w, "⏹️  Task interrupted, partial results below\n")
 	} else {
-		fmt.Printf("⚠️  Task completed with warnings\n")
+		fmt.Fprintf(w, "⚠️  Task completed with warnings\n")
+	}
+	fmt.Fprint(w, strings.Repeat("=", 60)+"\n\n")
+
+	fmt.Fprintf(w, "📊 Execution Summary:\n")
+	fmt.Fprintf(w, "   Steps executed: %d\n", result.StepsExecuted)
+	fmt.Fprintf(w, "   Duration: %v\n", result.Duration)
+	if agent.RunID() != "" {
+		fmt.Fprintf(w, "   Run ID: %s\n", agent.RunID())
 	}
-	fmt.Printf(strings.Repeat("=", 60) + "\n\n")
 
-	fmt.Printf("📊 Execution Summary:\n")
-	fmt.Printf("   Steps executed: %d\n", result.StepsExecuted)
-	fmt.Printf("   Duration: %v\n", result.Duration)
-	
 	if result.FinalState != "" {
-		fmt.Printf("   Final state: %s\n", result.FinalState)
+		fmt.Fprintf(w, "   Final state: %s\n", result.FinalState)
 	}
-	
+
 	if result.Error != nil {
-		fmt.Printf("   Error: %v\n", result.Error)
+		fmt.Fprintf(w, "   Error: %v\n", result.Error)
+	}
+
+	if len(result.Recoveries) > 0 {
+		fmt.Fprintf(w, "\n🩹 Recovery attempts:\n")
+		for _, rec := range result.Recoveries {
+			outcome := rec.Detail
+			if rec.Error != "" {
+				outcome = "failed: " + rec.Error
+			}
+			fmt.Fprintf(w, "   Step %d, %s: %s\n", rec.Step, rec.Strategy, outcome)
+		}
 	}
 
 	if result.Memory != nil {
-		fmt.Printf("\n🧠 Memory Summary:\n")
-		fmt.Printf("   Products viewed: %d\n", len(result.Memory.ProductURLs))
+		fmt.Fprintf(w, "\n🧠 Memory Summary:\n")
+		fmt.Fprintf(w, "   Products viewed: %d\n", len(result.Memory.ProductURLs))
 		if result.Memory.SelectedProduct != "" {
-			fmt.Printf("   Selected product: %s\n", result.Memory.SelectedProduct)
+			fmt.Fprintf(w, "   Selected product: %s\n", result.Memory.SelectedProduct)
+		}
+		fmt.Fprintf(w, "   Cart items: %d\n", result.Memory.CartCount())
+		for _, line := range result.Memory.CartItems {
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+		if len(result.Memory.ProductDetails) > 0 {
+			fmt.Fprintf(w, "   Product details extracted: %d\n", len(result.Memory.ProductDetails))
+			for _, d := range result.Memory.ProductDetails {
+				fmt.Fprintf(w, "      %s  %s  ₹%v  %.1f★  %s\n", d.ASIN, d.Title, d.Price, d.Rating, d.Availability)
+			}
+		}
+		if result.Memory.UserCredentials["email"] != "" || result.Memory.SessionData["signed_in"] == true {
+			fmt.Fprintf(w, "   User authenticated: Yes\n")
 		}
-		fmt.Printf("   Cart items: %d\n", len(result.Memory.CartItems))
-		if result.Memory.UserCredentials["email"] != "" {
-			fmt.Printf("   User authenticated: Yes\n")
+	}
+
+	if result.Output != nil && cfg.Output == "text" {
+		var output bytes.Buffer
+		json.Indent(&output, result.Output, "   ", "  ")
+		fmt.Fprintf(w, "\n📦 Output:\n   %s\n", output.String())
+	}
+
+	fmt.Fprintln(w)
+
+	if !result.Success && agent.RunID() != "" {
+		fmt.Fprintf(w, "♻️  Resume with: agent resume %s\n", agent.RunID())
+	}
+	if agent.RunID() != "" {
+		fmt.Fprintf(w, "📄 Report with: agent report %s\n\n", agent.RunID())
+	}
+}
+
+// signalContext returns a context cancelled by the first SIGINT or SIGTERM,
+// which stops the running step while still letting the partial result be
+// printed. Later signals get the default behaviour and kill the process.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-sigs:
+			signal.Stop(sigs)
+			fmt.Fprintf(os.Stderr, "\n⏹️  Received %v, stopping current step (repeat to force quit)...\n", sig)
+			cancel()
+		case <-ctx.Done():
 		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigs)
+		cancel()
+	}
+}
+
+func newLLMClient(cfg *config.Config) (llm.Client, error) {
+	apiKey, keyVars := llm.APIKeyFromEnv(cfg.LLMProvider)
+	if apiKey == "" && len(keyVars) > 0 {
+		return nil, fmt.Errorf("%s environment variable not set (this should be your %s API key)",
+			strings.Join(keyVars, " or "), cfg.LLMProvider)
 	}
-	
-	fmt.Println()
+
+	return llm.NewClient(llm.Options{
+		Provider:    cfg.LLMProvider,
+		APIKey:      apiKey,
+		Model:       cfg.LLMModel,
+		BaseURL:     cfg.LLMBaseURL,
+		FixturePath: cfg.LLMFixture,
+		RecordPath:  cfg.LLMRecord,
+	})
 }
 
 func printUsage() {
 	fmt.Println("Advanced Browser Agent - Complex E-commerce Automation")
-	fmt.Println("\nUsage: agent run \"<task description>\"")
+	fmt.Println("\nUsage: agent run [flags] \"<task description>\"")
+	fmt.Println("       agent resume <run-id>")
+	fmt.Println("       agent report [-o <file>] <run-dir | run-id>")
+	fmt.Println("       agent config show [flags]")
+	fmt.Println("       agent e2e [--headed] [--run <name>]")
+	fmt.Println("\nFlags (every config key has one; see agent run -help):")
+	fmt.Println("  --config <file>      YAML or TOML config file (default: agent.yaml if present)")
+	fmt.Println("  --max-steps <n>      Maximum number of steps (default: 150)")
+	fmt.Println("  --headless           Run the browser without a window")
+	fmt.Println("  --llm-provider <p>   openrouter (default), openai, anthropic, ollama or replay")
+	fmt.Println("  --llm-replay <file>  Replay LLM responses from a fixture file (no network)")
+	fmt.Println("  --llm-record <file>  Record live LLM responses to a fixture file")
+	fmt.Println("  --runs-dir <dir>     Directory for run checkpoints (default: runs)")
+	fmt.Println("  --session <name>     Reuse a saved browser session so login is skipped")
+	fmt.Println("  --sessions-dir <dir> Directory for saved sessions (default: sessions)")
+	fmt.Println("  --log-format <fmt>   Progress output: text (default) or json (one event per line)")
+	fmt.Println("  --artifacts <mode>   Page snapshots: steps (default), failures or off")
+	fmt.Println("  --output-schema <s>  JSON Schema of the task's answer, inline or a file")
+	fmt.Println("  --output <fmt>       Stdout: text (default) or json (only the task's output)")
+	fmt.Println("  --trace              Record a Playwright trace (runs/<run-id>/trace.zip)")
+	fmt.Println("  --video              Record a video of the browser (runs/<run-id>/video.webm)")
 	fmt.Println("\nExamples:")
 	fmt.Println("  Simple:")
 	fmt.Println("    agent run \"Go to amazon.in and search for laptops\"")
@@ -114,5 +346,9 @@ func printUsage() {
 	fmt.Println("  - Stop at payment screen (won't place actual orders)")
 	fmt.Println("  - Auto-recover from errors")
 	fmt.Println("\nEnvironment Variables:")
-	fmt.Println("  GEMINI_API_KEY - Your OpenRouter API key (required)")
-}
\ No newline at end of file
+	fmt.Println("  GEMINI_API_KEY    - Your OpenRouter API key (openrouter provider)")
+	fmt.Println("  OPENAI_API_KEY    - OpenAI API key (openai provider)")
+	fmt.Println("  ANTHROPIC_API_KEY - Anthropic API key (anthropic provider)")
+	fmt.Println("  AGENT_<KEY>       - Override any config key, e.g. AGENT_MAX_STEPS=80, AGENT_HEADLESS=true")
+	fmt.Println("\nPrecedence: flags > AGENT_* environment > config file > defaults")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"browser-agent/internal/config"
+	"browser-agent/internal/report"
+)
+
+// reportCommand renders a run directory into a self-contained HTML report.
+func reportCommand(args []string) {
+	path := config.FilePath(args)
+	cfg, err := config.Load(path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	out := fs.String("o", "", "output file (default: <run-dir>/report.html)")
+	fs.String("config", path, "config file (YAML or TOML)")
+	fs.StringVar(&cfg.RunsDir, "runs-dir", cfg.RunsDir, "directory holding runs, for looking up a run ID")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: agent report [-o <file>] <run-dir | run-id>")
+		os.Exit(1)
+	}
+
+	runDir := fs.Arg(0)
+	if _, err := os.Stat(runDir); os.IsNotExist(err) {
+		// Accept a bare run ID as printed at the end of a run.
+		runDir = filepath.Join(cfg.RunsDir, runDir)
+	}
+	if *out == "" {
+		*out = filepath.Join(runDir, "report.html")
+	}
+
+	if err := report.Write(runDir, *out); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("📄 Report written to %s\n", *out)
+}
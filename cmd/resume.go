@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"browser-agent/internal/amazon_agent"
+)
+
+// resumeCommand continues a checkpointed run from its last successful step.
+func resumeCommand(args []string) {
+	cfg, fs := loadConfig("resume", args)
+	fs.Parse(args)
+	resolveConfig(cfg)
+
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: agent resume [--runs-dir <dir>] [--session <name>] <run-id>")
+		os.Exit(1)
+	}
+	runID := fs.Arg(0)
+
+	progress, err := progressOutput(cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	observer, err := newObserver(cfg.LogFormat, progress)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	checkpoint, err := amazon_agent.LoadCheckpoint(cfg.RunsDir, runID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.Session == "" && checkpoint.Session != "" {
+		cfg.Set("session", checkpoint.Session, "checkpoint of run "+runID)
+	}
+
+	llmClient, err := newLLMClient(cfg)
+	if err != nil {
+		fmt.Printf("Error initializing LLM client: %v\n", err)
+		os.Exit(1)
+	}
+
+	agent, err := amazon_agent.NewAgent(cfg, llmClient)
+	if err != nil {
+		fmt.Printf("Error initializing agent: %v\n", err)
+		os.Exit(1)
+	}
+	defer agent.Close()
+	agent.SetObserver(observer)
+
+	if cfg.LogFormat == "text" {
+		fmt.Fprintf(progress, "\n🤖 Advanced Browser Agent Resuming...\n")
+		fmt.Fprintf(progress, "📋 Task: %s\n", checkpoint.TaskDescription)
+		fmt.Fprintf(progress, "🗂️  Run: %s (%s, last updated %s)\n\n", runID, checkpoint.Status, checkpoint.UpdatedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	result, err := agent.ResumeTask(ctx, checkpoint)
+	finish(cfg, agent, result, err)
+}
@@ -0,0 +1,393 @@
+package amazon_agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Action is one kind of plan step the executor can run. Registering an
+// action makes it available to the planner's prompt, the plan validator
+// and ExecuteStep at once.
+type Action interface {
+	// Name is what plan steps put in their "action" field.
+	Name() string
+	// Description tells the planner what the action does.
+	Description() string
+	// Schema describes the step fields the action takes.
+	Schema() ActionSchema
+	Execute(ctx context.Context, e *Executor, step Step, execCtx *ExecutionContext) (*ExecutionResult, error)
+}
+
+// Kinds of Step.Target.
+const (
+	TargetNone     = ""
+	TargetSelector = "selector"
+	TargetURL      = "url"
+)
+
+// ActionSchema describes the fields of a plan step for one action. The
+// *Doc fields are shown to the planner.
+type ActionSchema struct {
+	// Aliases are other names plans may use for the action.
+	Aliases []string
+
+	// Target is the kind of Step.Target the action takes, and
+	// TargetRequired whether it must be set.
+	Target         string
+	TargetRequired bool
+	TargetDoc      string
+
+	// ValueRequired means Step.Value must be set; ValueDuration that it is
+	// a duration if it is.
+	ValueRequired bool
+	ValueDuration bool
+	ValueDoc      string
+
+	// TargetOrValue means at least one of Target and Value must be set.
+	TargetOrValue bool
+
+	Params []ActionParam
+}
+
+// ActionParam is an entry of Step.Parameters. If Values is set, the
+// parameter must be one of them.
+type ActionParam struct {
+	Name    string
+	Example string
+	Values  []string
+}
+
+// ActionRegistry holds the actions the executor can run, by name and
+// alias, in registration order.
+type ActionRegistry struct {
+	actions []Action
+	byName  map[string]Action
+}
+
+func NewActionRegistry() *ActionRegistry {
+	return &ActionRegistry{byName: make(map[string]Action)}
+}
+
+// Register adds a. It is an error if its name or an alias is taken.
+func (r *ActionRegistry) Register(a Action) error {
+	names := append([]string{a.Name()}, a.Schema().Aliases...)
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("register action: empty name")
+		}
+		if _, ok := r.byName[name]; ok {
+			return fmt.Errorf("register action %s: %q is already registered", a.Name(), name)
+		}
+	}
+	for _, name := range names {
+		r.byName[name] = a
+	}
+	r.actions = append(r.actions, a)
+	return nil
+}
+
+// Lookup finds the action for a step's action name or alias.
+func (r *ActionRegistry) Lookup(name string) (Action, bool) {
+	a, ok := r.byName[name]
+	return a, ok
+}
+
+// Actions returns the registered actions in registration order.
+func (r *ActionRegistry) Actions() []Action {
+	return r.actions
+}
+
+// Names returns the registered action names, without aliases.
+func (r *ActionRegistry) Names() []string {
+	names := make([]string, len(r.actions))
+	for i, a := range r.actions {
+		names[i] = a.Name()
+	}
+	return names
+}
+
+// PromptList renders the actions as the planner prompt's "Available
+// actions" list: one "- name: description (fields)" line per action.
+func (r *ActionRegistry) PromptList() string {
+	var b strings.Builder
+	for _, a := range r.actions {
+		schema := a.Schema()
+		var fields []string
+		if schema.TargetDoc != "" {
+			fields = append(fields, "target: "+schema.TargetDoc)
+		}
+		if schema.ValueDoc != "" {
+			fields = append(fields, "value: "+schema.ValueDoc)
+		}
+		if len(schema.Params) > 0 {
+			params := make([]string, len(schema.Params))
+			for i, p := range schema.Params {
+				params[i] = fmt.Sprintf("%s: %q", p.Name, p.Example)
+			}
+			fields = append(fields, "parameters: {"+strings.Join(params, ", ")+"}")
+		}
+
+		fmt.Fprintf(&b, "- %s: %s", a.Name(), a.Description())
+		if len(fields) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(fields, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// builtinAction adapts one of the Executor's own methods to Action.
+type builtinAction struct {
+	name        string
+	description string
+	schema      ActionSchema
+	run         func(e *Executor, ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error)
+}
+
+func (a builtinAction) Name() string         { return a.name }
+func (a builtinAction) Description() string  { return a.description }
+func (a builtinAction) Schema() ActionSchema { return a.schema }
+
+func (a builtinAction) Execute(ctx context.Context, e *Executor, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+	return a.run(e, ctx, step, execCtx)
+}
+
+// searchParams bound the results pages read by the actions that collect
+// search results.
+var searchParams = []ActionParam{
+	{Name: "pages", Example: "3"},
+	{Name: "limit", Example: "60"},
+}
+
+// builtinActions are registered on every Executor, in the order the
+// planner sees them.
+func builtinActions() []Action {
+	return []Action{
+		builtinAction{
+			name:        "navigate",
+			description: "Go to URL",
+			schema:      ActionSchema{Target: TargetURL, TargetRequired: true, TargetDoc: "URL"},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeNavigate(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "click",
+			description: "Click element",
+			schema:      ActionSchema{Target: TargetSelector, TargetRequired: true, TargetDoc: "CSS selector"},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeClick(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "type",
+			description: "Type text",
+			// A search step without a value searches for the term its
+			// description or the task names.
+			schema: ActionSchema{
+				Target: TargetSelector, TargetRequired: true, TargetDoc: "selector",
+				ValueDoc: "text; optional when searching",
+				Params:   []ActionParam{{Name: "submit", Example: "true/false", Values: []string{"true", "false"}}},
+			},
+			run: func(e *Executor, ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+				if strings.Contains(strings.ToLower(step.Description), "search") {
+					return e.executeDynamicSearch(ctx, step, execCtx)
+				}
+				return e.executeType(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "wait",
+			description: "Wait for element or duration",
+			schema: ActionSchema{
+				Target: TargetSelector, TargetDoc: "selector optional",
+				ValueDuration: true, ValueDoc: "duration",
+			},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeWait(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "scroll",
+			description: "Scroll page",
+			schema: ActionSchema{Params: []ActionParam{
+				{Name: "direction", Example: "up/down/top/bottom", Values: []string{"up", "down", "top", "bottom"}},
+				{Name: "amount", Example: "500"},
+			}},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeScroll(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "go_back",
+			description: "Navigate back to previous page",
+			schema:      ActionSchema{Aliases: []string{"back"}},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeGoBack(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "apply_filter",
+			description: "Filter the search results with the site's refinements",
+			schema: ActionSchema{
+				ValueRequired: true,
+				ValueDoc:      `filters like "under ₹2000", "between ₹500 and ₹1500", "4 stars & up", "Prime", "brand Logitech", "delivery tomorrow", combinable`,
+			},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeApplyFilter(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "sort_results",
+			description: "Sort the search results",
+			schema: ActionSchema{
+				ValueRequired: true,
+				ValueDoc:      `"price low to high", "price high to low", "avg customer review", "newest" or "featured"`,
+			},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeSortResults(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "select_product",
+			description: "Intelligently select product",
+			schema: ActionSchema{
+				ValueDoc: `criteria like "first", "cheapest", "highest rated", "rating above 4", "under ₹500", "non-sponsored", combinable`,
+				Params:   searchParams,
+			},
+			run: func(e *Executor, ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeSelectProduct(ctx, step, execCtx)
+			},
+		},
+		builtinAction{
+			name:        "collect_products",
+			description: "Collect the products of the search results, across pages, for later steps",
+			schema: ActionSchema{
+				ValueDoc: "criteria to filter and rank by, like select_product's, optional",
+				Params:   searchParams,
+			},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeCollectProducts(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "select_variant",
+			description: "Choose the colour, size or other variation of the current product",
+			schema: ActionSchema{
+				ValueDoc: `options like "colour: black, size: L"; taken from the task if empty`,
+			},
+			run: func(e *Executor, ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeSelectVariant(ctx, step, execCtx)
+			},
+		},
+		builtinAction{
+			name:        "add_to_cart",
+			description: "Add current product to cart",
+			schema:      ActionSchema{Params: []ActionParam{{Name: "quantity", Example: "1"}}},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeAddToCart(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "set_cart_quantity",
+			description: "Change how many of a cart item to buy, on the cart page (0 removes it; item is its ASIN or title words, optional with one item in the cart)",
+			schema: ActionSchema{
+				ValueRequired: true,
+				ValueDoc:      "quantity",
+				Params:        []ActionParam{{Name: "item", Example: "USB cable"}},
+			},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeSetCartQuantity(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "remove_from_cart",
+			description: "Delete an item from the cart, on the cart page",
+			schema: ActionSchema{
+				ValueDoc: "ASIN or title words of the item, optional with one item in the cart",
+			},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeRemoveFromCart(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "read_cart",
+			description: "Read the cart page's items, quantities and subtotal into memory",
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeReadCart(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "proceed_checkout",
+			description: "Navigate to checkout from cart, first checking the cart holds just the items added",
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeProceedCheckout(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "login",
+			description: "Handle authentication - prompts user for email/password and fills them",
+			schema: ActionSchema{
+				Aliases: []string{"request_auth", "request_credentials"},
+				Params:  []ActionParam{{Name: "type", Example: "full"}},
+			},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeRequestAuth(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "fill_address",
+			description: "Fill shipping address form (prompts user)",
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeFillAddress(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "select_payment",
+			description: "Select payment method",
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeSelectPayment(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "extract_product",
+			description: "Read the current product page's title, ASIN, price, MRP, rating, seller, delivery, availability, bullets and images into memory",
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeExtractProduct(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "extract",
+			description: "Extract text",
+			schema:      ActionSchema{Target: TargetSelector, TargetRequired: true, TargetDoc: "selector"},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeExtract(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "verify",
+			description: "Verify page state",
+			schema: ActionSchema{
+				Target: TargetSelector, TargetDoc: "selector optional",
+				ValueDoc: "expected text", TargetOrValue: true,
+			},
+			run: func(e *Executor, ctx context.Context, step Step, _ *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeVerify(ctx, step)
+			},
+		},
+		builtinAction{
+			name:        "smart_action",
+			description: "Ask the LLM how to carry out the step's description on the current page",
+			run: func(e *Executor, ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+				return e.executeSmartAction(ctx, step, execCtx)
+			},
+		},
+	}
+}
+
+// RegisterAction adds a custom action to the agent's executor, making it
+// available to the planner and plan validation as well.
+func (a *Agent) RegisterAction(action Action) error {
+	return a.executor.Actions().Register(action)
+}
@@ -1,7 +1,12 @@
 package amazon_agent
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"time"
 
 	"browser-agent/internal/browser"
@@ -16,64 +21,175 @@ type Agent struct {
 	executor  *Executor
 	validator *Validator
 	memory    *AgentMemory
+
+	runID      string
+	runDir     string
+	checkpoint *Checkpoint
+
+	// sessionPath is the storage state file of Config.Session, if any.
+	sessionPath   string
+	sessionLoaded bool
+
+	// outputSchema is Config.OutputSchema, loaded; it replaces any schema
+	// the planner infers.
+	outputSchema json.RawMessage
+
+	// recovery are the strategies tried after repeated failures, and
+	// recoveries the attempts made so far in this run.
+	recovery   []RecoveryStrategy
+	recoveries []RecoveryAttempt
+
+	observer  Observer
+	eventLog  *os.File
+	eventSink *JSONLSink
 }
 
 type AgentMemory struct {
-	ProductURLs      []string
-	SelectedProduct  string
-	CartItems        []string
-	CurrentPage      string
-	UserCredentials  map[string]string
-	SessionData      map[string]interface{}
+	ProductURLs     []string `json:"product_urls"`
+	SelectedProduct string   `json:"selected_product,omitempty"`
+	// Products are the search results gathered by collect_products.
+	Products []SearchResult `json:"products,omitempty"`
+	// SelectedVariant is the variation chosen by select_variant.
+	SelectedVariant *ProductVariant `json:"selected_variant,omitempty"`
+	// CartItems are the cart lines the agent meant to buy, as added and
+	// changed by the cart actions.
+	CartItems []CartLine `json:"cart_items"`
+	// ProductDetails are the product pages read by extract_product.
+	ProductDetails []ProductDetails `json:"product_details,omitempty"`
+	// Cart is the cart page as last read, by read_cart or before checkout.
+	Cart            *CartContents          `json:"cart,omitempty"`
+	CurrentPage     string                 `json:"current_page,omitempty"`
+	UserCredentials map[string]string      `json:"user_credentials,omitempty"`
+	SessionData     map[string]interface{} `json:"session_data,omitempty"`
 }
 
 type TaskResult struct {
-	Success        bool
-	StepsExecuted  int
-	Duration       time.Duration
-	FinalState     string
-	Error          error
-	Memory         *AgentMemory
+	Success       bool
+	Interrupted   bool
+	StepsExecuted int
+	Duration      time.Duration
+	FinalState    string
+	Error         error
+	Memory        *AgentMemory
+	// Recoveries lists every recovery strategy tried during the run.
+	Recoveries []RecoveryAttempt
+	// Output is the task's answer, validated against the plan's output
+	// schema; nil when the task has no schema or didn't succeed.
+	Output json.RawMessage
 }
 
-func NewAgent(cfg *config.Config, apiKey string) (*Agent, error) {
-	br, err := browser.NewBrowser(cfg.Headless, cfg.SlowMo)
+func NewAgent(cfg *config.Config, llmClient llm.Client) (*Agent, error) {
+	switch cfg.ArtifactMode {
+	case ArtifactsSteps, ArtifactsFailures, ArtifactsOff:
+	default:
+		return nil, fmt.Errorf("unknown artifact mode %q (want steps, failures or off)", cfg.ArtifactMode)
+	}
+	if cfg.ValidationInterval < 0 {
+		return nil, fmt.Errorf("validation_interval must not be negative, got %d", cfg.ValidationInterval)
+	}
+	if cfg.SmartActionConfidence < 0 || cfg.SmartActionConfidence > 1 {
+		return nil, fmt.Errorf("smart_action_confidence must be between 0 and 1, got %v", cfg.SmartActionConfidence)
+	}
+	switch cfg.CartReconcile {
+	case CartRepair, CartFail, CartReconcileOff:
+	default:
+		return nil, fmt.Errorf("unknown cart_reconcile mode %q (want repair, fail or off)", cfg.CartReconcile)
+	}
+	if cfg.SearchPages < 1 || cfg.SearchLimit < 1 {
+		return nil, fmt.Errorf("search_pages and search_limit must be at least 1, got %d and %d", cfg.SearchPages, cfg.SearchLimit)
+	}
+
+	outputSchema, err := LoadOutputSchema(cfg.OutputSchema)
 	if err != nil {
-		return nil, fmt.Errorf("create browser: %w", err)
+		return nil, err
 	}
 
-	llmClient := llm.NewGeminiClient(apiKey)
+	var recovery []RecoveryStrategy
+	if cfg.EnableRecovery {
+		strategies, err := RecoveryStrategiesByName(cfg.RecoveryStrategies)
+		if err != nil {
+			return nil, err
+		}
+		recovery = strategies
+	}
 
-	memory := &AgentMemory{
-		ProductURLs:     make([]string, 0),
-		CartItems:       make([]string, 0),
-		UserCredentials: make(map[string]string),
-		SessionData:     make(map[string]interface{}),
+	var sessionPath, storageState string
+	if cfg.Session != "" {
+		path, exists, err := existingSession(cfg.SessionsDir, cfg.Session)
+		if err != nil {
+			return nil, err
+		}
+		sessionPath = path
+		if exists {
+			storageState = path
+		}
 	}
 
-	return &Agent{
+	br, err := browser.NewBrowser(browser.Options{
+		Headless:         cfg.Headless,
+		SlowMo:           cfg.SlowMo,
+		StorageStatePath: storageState,
+		Trace:            cfg.Trace,
+		Video:            cfg.Video,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create browser: %w", err)
+	}
+
+	memory := &AgentMemory{}
+	memory.ensureInitialized()
+	executor := NewExecutor(br, llmClient, memory)
+
+	a := &Agent{
 		config:    cfg,
 		browser:   br,
-		planner:   NewPlanner(llmClient),
-		executor:  NewExecutor(br, llmClient, memory),
+		planner:   NewPlanner(llmClient, executor.Actions()),
+		executor:  executor,
 		validator: NewValidator(llmClient),
 		memory:    memory,
-	}, nil
+
+		sessionPath:   sessionPath,
+		sessionLoaded: storageState != "",
+		outputSchema:  outputSchema,
+		recovery:      recovery,
+		observer:      NewConsoleRenderer(os.Stdout),
+	}
+	a.executor.emit = a.emit
+	a.executor.smartActionConfidence = cfg.SmartActionConfidence
+	a.executor.searchPages = cfg.SearchPages
+	a.executor.searchLimit = cfg.SearchLimit
+	a.executor.cartReconcile = cfg.CartReconcile
+	a.planner.emit = a.emit
+	return a, nil
 }
 
-func (a *Agent) ExecuteTask(taskDescription string) (*TaskResult, error) {
+// ExecuteTask plans and runs taskDescription within Config.TotalTimeout.
+// Cancelling ctx aborts the current step; the partial TaskResult is still
+// returned, with Interrupted set.
+func (a *Agent) ExecuteTask(ctx context.Context, taskDescription string) (*TaskResult, error) {
 	startTime := time.Now()
-	var lastValidationTime time.Time
-	validationInterval := 5
-	consecutiveFailures := 0
-	maxConsecutiveFailures := 3
 
-	plan, err := a.planner.CreatePlan(taskDescription)
+	runCtx, cancel := context.WithTimeout(ctx, a.config.TotalTimeout)
+	defer cancel()
+
+	if err := a.startRun(newRunID()); err != nil {
+		return nil, err
+	}
+	a.emit(Event{Type: EventTaskStarted, Task: &TaskEvent{Description: taskDescription, RunDir: a.runDir}})
+	a.announceSession()
+
+	plan, err := a.planner.CreatePlan(runCtx, taskDescription, a.outputSchema)
 	if err != nil {
+		if runCtx.Err() != nil {
+			return a.finishRun(runCtx, nil, a.stoppedResult(ctx, 0, startTime)), nil
+		}
 		return nil, fmt.Errorf("create plan: %w", err)
 	}
+	if a.outputSchema != nil {
+		plan.OutputSchema = a.outputSchema
+	}
 
-	fmt.Printf("📋 Generated plan with %d initial steps\n\n", len(plan.Steps))
+	a.emit(Event{Type: EventPlanCreated, Plan: plan})
 
 	executionContext := &ExecutionContext{
 		TaskDescription: taskDescription,
@@ -83,116 +199,246 @@ func (a *Agent) ExecuteTask(taskDescription string) (*TaskResult, error) {
 		Memory:          a.memory,
 	}
 
+	return a.finishRun(runCtx, executionContext, a.runPlan(ctx, runCtx, executionContext, startTime)), nil
+}
+
+// ResumeTask continues a checkpointed run: it restores the browser session
+// and memory, reopens the last page and carries on from the step after the
+// last successful one.
+func (a *Agent) ResumeTask(ctx context.Context, cp *Checkpoint) (*TaskResult, error) {
+	startTime := time.Now()
+
+	runCtx, cancel := context.WithTimeout(ctx, a.config.TotalTimeout)
+	defer cancel()
+
+	if cp.Status == CheckpointCompleted {
+		return nil, fmt.Errorf("run %s already completed", cp.RunID)
+	}
+	if cp.Plan == nil || len(cp.Plan.Steps) == 0 {
+		return nil, fmt.Errorf("checkpoint for run %s has no plan", cp.RunID)
+	}
+	if err := a.startRun(cp.RunID); err != nil {
+		return nil, err
+	}
+	// The resumed run's checkpoint keeps when the run was first started.
+	a.checkpoint = &Checkpoint{RunID: cp.RunID, CreatedAt: cp.CreatedAt}
+	a.announceSession()
+	if a.outputSchema != nil {
+		cp.Plan.OutputSchema = a.outputSchema
+	}
+
+	if cp.Memory != nil {
+		*a.memory = *cp.Memory
+		a.memory.ensureInitialized()
+	}
+
+	if cp.StorageState != "" {
+		if err := a.browser.LoadStorageState(runCtx, filepath.Join(a.runDir, cp.StorageState)); err != nil {
+			return nil, fmt.Errorf("restore browser session: %w", err)
+		}
+	}
+	if cp.CurrentURL != "" {
+		if err := a.browser.Navigate(runCtx, cp.CurrentURL); err != nil {
+			a.logf("⚠️  Could not reopen %s: %v\n", cp.CurrentURL, err)
+		}
+	}
+
+	executionContext := &ExecutionContext{
+		TaskDescription: cp.TaskDescription,
+		Plan:            cp.Plan,
+		ExecutedSteps:   cp.ExecutedSteps,
+		CurrentStepNum:  min(cp.NextStep, len(cp.Plan.Steps)),
+		Memory:          a.memory,
+	}
+
+	a.emit(Event{Type: EventTaskStarted, Task: &TaskEvent{
+		Description:   cp.TaskDescription,
+		RunDir:        a.runDir,
+		Resumed:       true,
+		StartStep:     executionContext.CurrentStepNum,
+		TotalSteps:    len(cp.Plan.Steps),
+		StepsExecuted: len(cp.ExecutedSteps),
+	}})
+
+	return a.finishRun(runCtx, executionContext, a.runPlan(ctx, runCtx, executionContext, startTime)), nil
+}
+
+// runPlan is the step loop shared by ExecuteTask and ResumeTask. It saves a
+// checkpoint after every step so the run can be resumed after a crash.
+func (a *Agent) runPlan(ctx, runCtx context.Context, executionContext *ExecutionContext, startTime time.Time) *TaskResult {
+	var lastValidationTime time.Time
+	validationInterval := a.config.ValidationInterval
+	consecutiveFailures := 0
+	maxConsecutiveFailures := 3
+
+	plan := executionContext.Plan
+	// resumeStep is where a resumed run restarts: the step after the last
+	// successful one in the current plan. resumeURL is the page the browser
+	// was on then.
+	resumeStep := executionContext.CurrentStepNum
+	resumeURL := a.browser.URL()
+	a.saveCheckpoint(executionContext, resumeStep, CheckpointRunning)
+
 	for executionContext.CurrentStepNum < len(plan.Steps) && executionContext.CurrentStepNum < a.config.MaxSteps {
-		if time.Since(startTime) > a.config.TotalTimeout {
-			return &TaskResult{
-				Success:       false,
-				StepsExecuted: len(executionContext.ExecutedSteps),
-				Duration:      time.Since(startTime),
-				Error:         fmt.Errorf("total timeout exceeded"),
-				Memory:        a.memory,
-			}, nil
+		if runCtx.Err() != nil {
+			return a.stoppedResult(ctx, len(executionContext.ExecutedSteps), startTime)
 		}
 
 		step := plan.Steps[executionContext.CurrentStepNum]
-		fmt.Printf("🔄 Step %d/%d: %s\n", executionContext.CurrentStepNum+1, len(plan.Steps), step.Description)
+		stepNum, stepTotal := executionContext.CurrentStepNum+1, len(plan.Steps)
+		// seq numbers artifacts across replans and resumes.
+		seq := len(executionContext.ExecutedSteps) + 1
+		a.emit(Event{Type: EventStepStarted, Step: &StepEvent{Number: stepNum, Total: stepTotal, Step: step}})
 
-		executionResult, err := a.executor.ExecuteStep(step, executionContext)
+		before := a.beforeArtifact(seq)
+		stepStart := time.Now()
+		executionResult, err := a.attemptStep(runCtx, step, executionContext, a.retryPolicy(step.Action).Timeout)
+		stepDuration := time.Since(stepStart)
 
 		executedStep := ExecutedStep{
 			Step:      step,
 			Success:   err == nil,
 			Error:     err,
 			Timestamp: time.Now(),
+			Artifacts: a.stepArtifacts(seq, before, ArtifactAfter, err),
 		}
 		executionContext.ExecutedSteps = append(executionContext.ExecutedSteps, executedStep)
+		a.emit(Event{Type: EventStepFinished, Step: &StepEvent{
+			Number:    stepNum,
+			Total:     stepTotal,
+			Step:      step,
+			Attempt:   1,
+			Success:   err == nil,
+			Stopped:   runCtx.Err() != nil,
+			Error:     errString(err),
+			Duration:  stepDuration,
+			Artifacts: executedStep.Artifacts,
+		}})
+
+		if runCtx.Err() != nil {
+			return a.stoppedResult(ctx, len(executionContext.ExecutedSteps), startTime)
+		}
 
 		if err != nil {
-			fmt.Printf("   ❌ Failed: %v\n", err)
 			consecutiveFailures++
 
-			if consecutiveFailures >= maxConsecutiveFailures {
-				fmt.Printf("   🔄 Too many consecutive failures, attempting recovery...\n")
-				pageState, _ := a.browser.GetPageState()
-				recoveryPlan, recovErr := a.planner.CreateRecoveryPlan(executionContext, pageState, err.Error())
-				if recovErr == nil && recoveryPlan != nil {
-					plan = recoveryPlan
-					executionContext.Plan = recoveryPlan
-					executionContext.CurrentStepNum = 0
+			if consecutiveFailures >= maxConsecutiveFailures && len(a.recovery) > 0 {
+				a.logf("   🔄 Too many consecutive failures, attempting recovery...\n")
+				recovery := a.recover(runCtx, &RecoveryContext{
+					Browser:    a.browser,
+					Planner:    a.planner,
+					Execution:  executionContext,
+					FailedStep: executionContext.CurrentStepNum,
+					Err:        err,
+					ResumeStep: resumeStep,
+					ResumeURL:  resumeURL,
+				})
+				if runCtx.Err() != nil {
+					return a.stoppedResult(ctx, len(executionContext.ExecutedSteps), startTime)
+				}
+				if recovery != nil {
+					if recovery.Plan != nil {
+						recovery.Plan.OutputSchema = plan.OutputSchema
+						plan = recovery.Plan
+						executionContext.Plan = recovery.Plan
+						resumeStep = recovery.NextStep
+					} else {
+						resumeStep = min(resumeStep, recovery.NextStep)
+					}
+					executionContext.CurrentStepNum = recovery.NextStep
 					consecutiveFailures = 0
-					fmt.Printf("   📋 Recovery plan with %d steps\n", len(recoveryPlan.Steps))
+					a.saveCheckpoint(executionContext, resumeStep, CheckpointRunning)
 					continue
 				}
 			}
 
 			if step.Critical {
-				fmt.Printf("   🔄 Retrying critical step...\n")
-				time.Sleep(2 * time.Second)
-				_, retryErr := a.executor.ExecuteStep(step, executionContext)
-				if retryErr == nil {
-					fmt.Printf("   ✓ Retry successful\n")
-					err = nil
-					executedStep.Success = true
-					executedStep.Error = nil
-					consecutiveFailures = 0
-				} else {
+				executionResult, err = a.retryCriticalStep(runCtx, executionContext, stepNum, stepTotal, seq, err)
+				if runCtx.Err() != nil {
+					return a.stoppedResult(ctx, len(executionContext.ExecutedSteps), startTime)
+				}
+				if err != nil {
+					retries := executionContext.ExecutedSteps[len(executionContext.ExecutedSteps)-1].Retries
+					a.saveCheckpoint(executionContext, resumeStep, CheckpointRunning)
 					return &TaskResult{
 						Success:       false,
 						StepsExecuted: len(executionContext.ExecutedSteps),
 						Duration:      time.Since(startTime),
-						Error:         fmt.Errorf("critical step failed after retry: %w", retryErr),
+						Error:         fmt.Errorf("critical step failed after %d retries: %w", retries, err),
 						Memory:        a.memory,
-					}, nil
+					}
 				}
 			}
-		} else {
-			fmt.Printf("   ✓ Completed\n")
+		}
+
+		if err == nil {
 			consecutiveFailures = 0
 
 			// Store execution result in memory if available
 			if executionResult != nil && executionResult.Data != nil {
 				a.updateMemory(executionResult.Data)
+				if signedIn, _ := executionResult.Data["signed_in"].(bool); signedIn {
+					a.saveSession()
+				}
 			}
 		}
 
 		executionContext.CurrentStepNum++
+		if err == nil {
+			resumeStep = executionContext.CurrentStepNum
+			resumeURL = a.browser.URL()
+		}
+		a.saveCheckpoint(executionContext, resumeStep, CheckpointRunning)
+
+		if err == nil && validationInterval > 0 && executionContext.CurrentStepNum%validationInterval == 0 && time.Since(lastValidationTime) > 10*time.Second {
+			pageState, stateErr := a.browser.GetPageState(runCtx)
+			if stateErr != nil {
+				pageState = &browser.PageState{}
+			}
+			validationResult, valErr := a.validator.ValidateProgress(runCtx, executionContext, pageState)
 
-		if err == nil && (executionContext.CurrentStepNum%validationInterval == 0) && time.Since(lastValidationTime) > 10*time.Second {
-			pageState, _ := a.browser.GetPageState()
-			validationResult, valErr := a.validator.ValidateProgress(executionContext, pageState)
+			if runCtx.Err() != nil {
+				return a.stoppedResult(ctx, len(executionContext.ExecutedSteps), startTime)
+			}
 
 			if valErr != nil {
-				fmt.Printf("   ⚠️  Validation error: %v\n", valErr)
+				a.emit(Event{Type: EventValidation, Validation: &ValidationEvent{Error: valErr.Error()}})
 			} else if validationResult != nil {
+				a.emit(Event{Type: EventValidation, Validation: &ValidationEvent{
+					IsComplete:      validationResult.IsComplete,
+					NeedsReplanning: validationResult.NeedsReplanning,
+					Message:         validationResult.Message,
+					Confidence:      validationResult.Confidence,
+					Phase:           validationResult.CurrentPhase,
+				}})
 				lastValidationTime = time.Now()
 				if validationResult.IsComplete {
-					fmt.Printf("\n🎉 Task completed: %s\n", validationResult.Message)
 					return &TaskResult{
 						Success:       true,
 						StepsExecuted: len(executionContext.ExecutedSteps),
 						Duration:      time.Since(startTime),
 						FinalState:    validationResult.Message,
 						Memory:        a.memory,
-					}, nil
+					}
 				}
 
 				if validationResult.NeedsReplanning {
-					fmt.Printf("   🔄 Replanning required: %s\n", validationResult.Message)
-					newPlan, replanErr := a.planner.Replan(executionContext, validationResult.Message)
-					if replanErr != nil {
-						fmt.Printf("   ⚠️  Replan failed: %v, continuing with original plan\n", replanErr)
-					} else {
+					newPlan, replanErr := a.planner.Replan(runCtx, executionContext, validationResult.Message)
+					a.emit(Event{Type: EventReplan, Replan: &ReplanEvent{Reason: validationResult.Message, Plan: newPlan, Error: errString(replanErr)}})
+					if replanErr == nil {
+						newPlan.OutputSchema = plan.OutputSchema
 						plan = newPlan
 						executionContext.Plan = newPlan
 						executionContext.CurrentStepNum = 0
-						fmt.Printf("   📋 New plan with %d steps\n", len(newPlan.Steps))
+						resumeStep = 0
+						a.saveCheckpoint(executionContext, resumeStep, CheckpointRunning)
 						continue
 					}
 				}
 			}
 		}
 
-		time.Sleep(500 * time.Millisecond)
+		browser.Sleep(runCtx, 500*time.Millisecond)
 	}
 
 	if executionContext.CurrentStepNum >= a.config.MaxSteps {
@@ -202,7 +448,7 @@ func (a *Agent) ExecuteTask(taskDescription string) (*TaskResult, error) {
 			Duration:      time.Since(startTime),
 			Error:         fmt.Errorf("max steps exceeded"),
 			Memory:        a.memory,
-		}, nil
+		}
 	}
 
 	return &TaskResult{
@@ -211,7 +457,161 @@ func (a *Agent) ExecuteTask(taskDescription string) (*TaskResult, error) {
 		Duration:      time.Since(startTime),
 		FinalState:    "All planned steps completed",
 		Memory:        a.memory,
-	}, nil
+	}
+}
+
+// finishRun composes the output of a successful run whose plan has an
+// output schema, records the final checkpoint status for result and
+// publishes the task_done event. Failed and interrupted runs keep their
+// resume point; a run whose output doesn't match its schema fails, and
+// resuming it composes the output again.
+func (a *Agent) finishRun(ctx context.Context, executionContext *ExecutionContext, result *TaskResult) *TaskResult {
+	if result.Success && executionContext != nil && len(executionContext.Plan.OutputSchema) > 0 {
+		a.logf("📦 Composing task output...\n")
+		output, err := a.planner.ComposeOutput(ctx, executionContext.TaskDescription, executionContext.Plan.OutputSchema, a.memory)
+		if err != nil {
+			result.Success = false
+			result.Error = fmt.Errorf("task output: %w", err)
+		} else {
+			result.Output = output
+		}
+	}
+
+	status := CheckpointFailed
+	switch {
+	case result.Success:
+		status = CheckpointCompleted
+	case result.Interrupted:
+		status = CheckpointInterrupted
+	}
+	a.updateCheckpointStatus(status)
+	a.saveSession()
+
+	result.Recoveries = a.recoveries
+	a.emit(Event{Type: EventTaskDone, Result: &ResultEvent{
+		Success:       result.Success,
+		Interrupted:   result.Interrupted,
+		StepsExecuted: result.StepsExecuted,
+		Duration:      result.Duration,
+		FinalState:    result.FinalState,
+		Error:         errString(result.Error),
+		Recoveries:    len(result.Recoveries),
+		Output:        result.Output,
+	}})
+	return result
+}
+
+func (a *Agent) announceSession() {
+	switch {
+	case a.sessionLoaded:
+		a.logf("🔑 Using saved session: %s\n", a.config.Session)
+	case a.sessionPath != "":
+		a.logf("🔑 New session %s will be saved after login\n", a.config.Session)
+	}
+}
+
+// RunID identifies the current run's checkpoint directory; it is empty
+// until ExecuteTask or ResumeTask starts.
+func (a *Agent) RunID() string {
+	return a.runID
+}
+
+// stoppedResult is the partial result of a run cut short by its context. A
+// cancelled parent means the caller interrupted the run; otherwise the
+// TotalTimeout deadline expired.
+func (a *Agent) stoppedResult(parent context.Context, stepsExecuted int, startTime time.Time) *TaskResult {
+	result := &TaskResult{
+		Success:       false,
+		StepsExecuted: stepsExecuted,
+		Duration:      time.Since(startTime),
+		Memory:        a.memory,
+	}
+	if parent.Err() != nil {
+		result.Interrupted = true
+		result.Error = fmt.Errorf("task interrupted: %w", parent.Err())
+	} else {
+		result.Error = fmt.Errorf("total timeout exceeded")
+	}
+	return result
+}
+
+func (m *AgentMemory) ensureInitialized() {
+	if m.ProductURLs == nil {
+		m.ProductURLs = make([]string, 0)
+	}
+	if m.CartItems == nil {
+		m.CartItems = make([]CartLine, 0)
+	}
+	if m.UserCredentials == nil {
+		m.UserCredentials = make(map[string]string)
+	}
+	if m.SessionData == nil {
+		m.SessionData = make(map[string]interface{})
+	}
+}
+
+// addToCart records line as added to the cart, adding to the quantity of
+// the same product if it is already there.
+func (m *AgentMemory) addToCart(line CartLine) {
+	i := slices.IndexFunc(m.CartItems, func(existing CartLine) bool { return line.ASIN != "" && existing.ASIN == line.ASIN })
+	if i >= 0 {
+		m.CartItems[i].Quantity += line.Quantity
+	} else {
+		m.CartItems = append(m.CartItems, line)
+	}
+}
+
+// setCartLine records line's quantity as the one wanted, removing the
+// line at 0.
+func (m *AgentMemory) setCartLine(line CartLine) {
+	i := slices.IndexFunc(m.CartItems, func(existing CartLine) bool { return existing.ASIN == line.ASIN })
+	switch {
+	case i < 0 && line.Quantity > 0:
+		m.CartItems = append(m.CartItems, line)
+	case i >= 0 && line.Quantity == 0:
+		m.CartItems = slices.Delete(m.CartItems, i, i+1)
+	case i >= 0:
+		m.CartItems[i].Quantity = line.Quantity
+	}
+}
+
+// CartCount is the number of items in the cart lines, counting quantities.
+func (m *AgentMemory) CartCount() int {
+	n := 0
+	for _, line := range m.CartItems {
+		n += line.Quantity
+	}
+	return n
+}
+
+// addProductDetails records d, replacing earlier details of the same
+// product: the same ASIN or, for a page whose ASIN wasn't found, the same
+// URL.
+func (m *AgentMemory) addProductDetails(d ProductDetails) {
+	i := slices.IndexFunc(m.ProductDetails, func(existing ProductDetails) bool {
+		if d.ASIN != "" {
+			return existing.ASIN == d.ASIN
+		}
+		return d.URL != "" && existing.ASIN == "" && existing.URL == d.URL
+	})
+	if i >= 0 {
+		m.ProductDetails[i] = d
+	} else {
+		m.ProductDetails = append(m.ProductDetails, d)
+	}
+}
+
+// addProducts merges products into the collected ones, replacing those
+// with the same ASIN.
+func (m *AgentMemory) addProducts(products []SearchResult) {
+	for _, p := range products {
+		i := slices.IndexFunc(m.Products, func(existing SearchResult) bool { return existing.ASIN == p.ASIN })
+		if i >= 0 {
+			m.Products[i] = p
+		} else {
+			m.Products = append(m.Products, p)
+		}
+	}
 }
 
 func (a *Agent) updateMemory(data map[string]interface{}) {
@@ -221,16 +621,47 @@ func (a *Agent) updateMemory(data map[string]interface{}) {
 	if selected, ok := data["selected_product"].(string); ok {
 		a.memory.SelectedProduct = selected
 	}
-	if cartItem, ok := data["cart_item"].(string); ok {
-		a.memory.CartItems = append(a.memory.CartItems, cartItem)
+	if variant, ok := data["variant"].(*ProductVariant); ok {
+		a.memory.SelectedVariant = variant
+	}
+	if products, ok := data["products"].([]SearchResult); ok {
+		a.memory.addProducts(products)
+	}
+	if line, ok := data["cart_line"].(CartLine); ok {
+		a.memory.addToCart(line)
+	}
+	if line, ok := data["cart_set"].(CartLine); ok {
+		a.memory.setCartLine(line)
+	}
+	if details, ok := data["product_details"].(*ProductDetails); ok {
+		a.memory.addProductDetails(*details)
+	}
+	if cart, ok := data["cart"].(*CartContents); ok {
+		a.memory.Cart = cart
 	}
 	if page, ok := data["current_page"].(string); ok {
 		a.memory.CurrentPage = page
 	}
+	if signedIn, ok := data["signed_in"].(bool); ok {
+		a.memory.SessionData["signed_in"] = signedIn
+	}
 }
 
+// SetPrompter replaces the terminal prompts used for login and address
+// details, e.g. with a StaticPrompter for unattended runs.
+func (a *Agent) SetPrompter(p Prompter) {
+	a.executor.prompter = p
+}
+
+// Close shuts the browser down, saving its trace and video into the run
+// directory when they are enabled.
 func (a *Agent) Close() {
 	if a.browser != nil {
-		a.browser.Close()
+		if err := a.browser.Close(); err != nil {
+			a.logf("⚠️  %v\n", err)
+		}
+		a.browser = nil
+		a.announceRecordings()
 	}
-}
\ No newline at end of file
+	a.closeEventLog()
+}
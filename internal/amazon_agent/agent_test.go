@@ -0,0 +1,30 @@
+package amazon_agent
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAddProductDetails(t *testing.T) {
+	m := &AgentMemory{}
+	for _, d := range []ProductDetails{
+		{ASIN: "B0MOCK0201", Title: "Headphones", Price: 999},
+		{URL: "https://www.amazon.in/dp/x", Title: "No ASIN"},
+		{URL: "https://www.amazon.in/dp/y", Title: "Another without ASIN"},
+		{ASIN: "B0MOCK0201", Title: "Headphones", Price: 899},
+		{URL: "https://www.amazon.in/dp/x", Title: "No ASIN, read again"},
+	} {
+		m.addProductDetails(d)
+	}
+
+	var titles []string
+	for _, d := range m.ProductDetails {
+		titles = append(titles, d.Title)
+	}
+	if want := []string{"Headphones", "No ASIN, read again", "Another without ASIN"}; !slices.Equal(titles, want) {
+		t.Errorf("titles = %q, want %q", titles, want)
+	}
+	if m.ProductDetails[0].Price != 899 {
+		t.Errorf("price = %v, want the later extraction's 899", m.ProductDetails[0].Price)
+	}
+}
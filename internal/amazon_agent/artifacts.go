@@ -0,0 +1,208 @@
+package amazon_agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"browser-agent/internal/browser"
+)
+
+// Artifact modes for Config.ArtifactMode.
+const (
+	ArtifactsSteps    = "steps"
+	ArtifactsFailures = "failures"
+	ArtifactsOff      = "off"
+)
+
+// Artifact labels.
+const (
+	ArtifactBefore  = "before"
+	ArtifactAfter   = "after"
+	ArtifactFailure = "failure"
+)
+
+const (
+	artifactsDir = "artifacts"
+	eventLogFile = "events.jsonl"
+
+	// maxSnapshotHTML caps a trimmed HTML snapshot; Amazon result pages
+	// are several megabytes even without scripts.
+	maxSnapshotHTML = 512 * 1024
+)
+
+// Artifact is a snapshot of the page taken around a step. File paths are
+// relative to the run directory.
+type Artifact struct {
+	Label      string    `json:"label"`
+	URL        string    `json:"url"`
+	Title      string    `json:"title"`
+	Screenshot string    `json:"screenshot,omitempty"`
+	HTML       string    `json:"html,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+var (
+	noisyElements []*regexp.Regexp
+	htmlComments  = regexp.MustCompile(`(?s)<!--.*?-->`)
+	blankRuns     = regexp.MustCompile(`\s{2,}`)
+)
+
+func init() {
+	for _, tag := range []string{"script", "style", "noscript", "svg", "template"} {
+		noisyElements = append(noisyElements, regexp.MustCompile(`(?is)<`+tag+`\b.*?</`+tag+`\s*>`))
+	}
+}
+
+// trimHTML drops scripts, styles, inline SVG and comments and collapses
+// whitespace, keeping the markup the selectors work on.
+func trimHTML(html string) string {
+	for _, re := range noisyElements {
+		html = re.ReplaceAllString(html, "")
+	}
+	html = htmlComments.ReplaceAllString(html, "")
+	html = blankRuns.ReplaceAllString(html, "\n")
+	if len(html) > maxSnapshotHTML {
+		// Cut at the start of a character, not inside one.
+		end := maxSnapshotHTML
+		for end > 0 && !utf8.RuneStart(html[end]) {
+			end--
+		}
+		html = html[:end] + "\n<!-- snapshot truncated -->"
+	}
+	return html
+}
+
+// captureArtifact saves a screenshot, trimmed HTML and page metadata as
+// artifacts/<seq>-<label>.*. Like checkpoints it ignores the run's context
+// so a failed or interrupted step is still captured. Capture problems are
+// recorded on the artifact rather than failing the step.
+func (a *Agent) captureArtifact(seq int, label string) *Artifact {
+	if a.runDir == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	dir := filepath.Join(a.runDir, artifactsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		a.logf("   ⚠️  Could not create artifacts directory: %v\n", err)
+		return nil
+	}
+
+	base := fmt.Sprintf("%04d-%s", seq, label)
+	artifact := &Artifact{Label: label, Time: time.Now()}
+	var problems []string
+
+	if state, err := a.browser.GetPageState(ctx); err == nil {
+		artifact.URL = state.URL
+		artifact.Title = state.Title
+	} else {
+		artifact.URL = a.browser.URL()
+		problems = append(problems, fmt.Sprintf("page state: %v", err))
+	}
+
+	if png, err := a.browser.Screenshot(ctx); err != nil {
+		problems = append(problems, fmt.Sprintf("screenshot: %v", err))
+	} else if err := os.WriteFile(filepath.Join(dir, base+".png"), png, 0o644); err != nil {
+		problems = append(problems, fmt.Sprintf("screenshot: %v", err))
+	} else {
+		artifact.Screenshot = filepath.ToSlash(filepath.Join(artifactsDir, base+".png"))
+	}
+
+	if html, err := a.browser.HTML(ctx); err != nil {
+		problems = append(problems, fmt.Sprintf("html: %v", err))
+	} else if err := os.WriteFile(filepath.Join(dir, base+".html"), []byte(trimHTML(html)), 0o644); err != nil {
+		problems = append(problems, fmt.Sprintf("html: %v", err))
+	} else {
+		artifact.HTML = filepath.ToSlash(filepath.Join(artifactsDir, base+".html"))
+	}
+
+	artifact.Error = strings.Join(problems, "; ")
+
+	if data, err := json.MarshalIndent(artifact, "", "  "); err == nil {
+		os.WriteFile(filepath.Join(dir, base+".json"), data, 0o644)
+	}
+	return artifact
+}
+
+// stepArtifacts captures the snapshot taken after a step attempt, according
+// to Config.ArtifactMode. Failed steps are labelled "failure".
+func (a *Agent) stepArtifacts(seq int, before *Artifact, label string, stepErr error) []Artifact {
+	var artifacts []Artifact
+	if before != nil {
+		artifacts = append(artifacts, *before)
+	}
+
+	mode := a.config.ArtifactMode
+	if mode == ArtifactsOff || (mode == ArtifactsFailures && stepErr == nil) {
+		return artifacts
+	}
+	if stepErr != nil {
+		label = strings.Replace(label, ArtifactAfter, ArtifactFailure, 1)
+	}
+	if after := a.captureArtifact(seq, label); after != nil {
+		artifacts = append(artifacts, *after)
+	}
+	return artifacts
+}
+
+func (a *Agent) beforeArtifact(seq int) *Artifact {
+	if a.config.ArtifactMode != ArtifactsSteps {
+		return nil
+	}
+	return a.captureArtifact(seq, ArtifactBefore)
+}
+
+// announceRecordings points at the trace and video Browser.Close saved
+// into the run directory.
+func (a *Agent) announceRecordings() {
+	if a.runDir == "" {
+		return
+	}
+	if a.config.Trace {
+		if path := filepath.Join(a.runDir, browser.TraceFile); fileExists(path) {
+			a.logf("🧭 Trace saved: %s (view with: npx playwright show-trace %s)\n", path, path)
+		}
+	}
+	if a.config.Video {
+		if path := filepath.Join(a.runDir, browser.VideoFile); fileExists(path) {
+			a.logf("🎬 Video saved: %s\n", path)
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// openEventLog starts (or, for a resumed run, continues) the run's
+// events.jsonl, which records every event next to the artifacts it links.
+func (a *Agent) openEventLog() {
+	a.closeEventLog()
+
+	f, err := os.OpenFile(filepath.Join(a.runDir, eventLogFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		a.logf("⚠️  Could not open run log: %v\n", err)
+		return
+	}
+	a.eventLog = f
+	a.eventSink = NewJSONLSink(f)
+}
+
+func (a *Agent) closeEventLog() {
+	if a.eventLog != nil {
+		a.eventLog.Close()
+	}
+	a.eventLog = nil
+	a.eventSink = nil
+}
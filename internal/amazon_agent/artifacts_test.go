@@ -0,0 +1,19 @@
+package amazon_agent
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTrimHTMLKeepsUTF8(t *testing.T) {
+	// "₹" is three bytes; the limit falls inside one of them.
+	html := "<p>" + strings.Repeat("₹", maxSnapshotHTML/3+1) + "</p>"
+	got := trimHTML(html)
+	if !utf8.ValidString(got) {
+		t.Fatal("trimmed snapshot is not valid UTF-8")
+	}
+	if !strings.HasSuffix(got, "<!-- snapshot truncated -->") {
+		t.Errorf("trimmed snapshot lacks the truncation marker: ...%s", got[len(got)-40:])
+	}
+}
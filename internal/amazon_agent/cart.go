@@ -0,0 +1,655 @@
+package amazon_agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"browser-agent/internal/browser"
+)
+
+// Cart reconciliation modes for Config.CartReconcile.
+const (
+	CartRepair       = "repair"
+	CartFail         = "fail"
+	CartReconcileOff = "off"
+)
+
+// CartLine is one product in the cart and how many of it.
+type CartLine struct {
+	ASIN  string `json:"asin"`
+	Title string `json:"title"`
+	// UnitPrice is in rupees; zero when the page showed no price.
+	UnitPrice float64 `json:"unit_price,omitempty"`
+	Quantity  int     `json:"quantity"`
+}
+
+func (l CartLine) String() string {
+	title := l.Title
+	if title == "" {
+		title = l.ASIN
+	}
+	return fmt.Sprintf("%d × %s", l.Quantity, truncate(title, 60))
+}
+
+// CartContents is what the cart page shows.
+type CartContents struct {
+	Lines []CartLine `json:"lines"`
+	// Subtotal is in rupees, and Count the number of items it is for, as
+	// in "Subtotal (3 items): ₹1,697".
+	Subtotal float64 `json:"subtotal"`
+	Count    int     `json:"count"`
+	// Rendered is whether the page has the cart's container or subtotal;
+	// a page still loading has neither, and so no lines either.
+	Rendered bool `json:"rendered"`
+}
+
+// productLineScript reads the product of the current product page and sets
+// its quantity dropdown to the given quantity, reporting the largest
+// quantity offered when it can't.
+const productLineScript = `
+(quantity) => {
+    const number = (text) => {
+        const m = (text || '').replace(/,/g, '').match(/\d+(\.\d+)?/);
+        return m ? parseFloat(m[0]) : 0;
+    };
+    const asinInput = document.querySelector('input#ASIN, input[name="ASIN"]');
+    const dp = document.querySelector('#dp[data-asin]');
+    const m = location.href.match(/\/(?:dp|gp\/product)\/([A-Z0-9]{10})/);
+    const title = document.querySelector('#productTitle, #title');
+    const price = document.querySelector('#corePrice_feature_div .a-price:not(.a-text-price) .a-offscreen, #corePriceDisplay_desktop_feature_div .a-price:not(.a-text-price) .a-offscreen, #priceblock_ourprice, #priceblock_dealprice');
+
+    const line = {
+        asin: (asinInput && asinInput.value) || (dp && dp.getAttribute('data-asin')) || (m ? m[1] : ''),
+        title: title ? title.textContent.replace(/\s+/g, ' ').trim() : document.title,
+        unit_price: price ? number(price.textContent) : 0,
+        quantity: quantity
+    };
+
+    const select = document.querySelector('select#quantity, select[name="quantity"]');
+    if (!select) {
+        return { line: line, set: quantity === 1, max: 1 };
+    }
+    const values = Array.from(select.options).map(o => parseInt(o.value, 10) || 0);
+    if (!values.includes(quantity)) {
+        return { line: line, set: false, max: Math.max(...values) };
+    }
+    select.value = String(quantity);
+    select.dispatchEvent(new Event('change', { bubbles: true }));
+    return { line: line, set: true, max: Math.max(...values) };
+}
+`
+
+// cartLineSelector matches the lines of the active cart, not those saved
+// for later.
+const cartLineSelector = `#sc-active-cart [data-asin].sc-list-item, #activeCartViewForm [data-asin].sc-list-item, #sc-active-cart [data-asin][data-itemtype="active"]`
+
+// cartScript reads the lines and subtotal of the cart page.
+const cartScript = `
+(selector) => {
+    const number = (text) => {
+        const m = (text || '').replace(/,/g, '').match(/\d+(\.\d+)?/);
+        return m ? parseFloat(m[0]) : 0;
+    };
+    const lines = [];
+    const seen = new Set();
+    for (const el of document.querySelectorAll(selector)) {
+        const asin = el.getAttribute('data-asin');
+        if (!asin || seen.has(asin)) continue;
+        seen.add(asin);
+
+        const title = el.querySelector('.sc-product-title, .a-truncate-full, .sc-product-link');
+        const price = el.querySelector('.sc-product-price, .sc-item-price-block .a-offscreen, .a-price .a-offscreen');
+        const box = el.querySelector('input[name="quantityBox"]');
+        const prompt = el.querySelector('.a-dropdown-prompt, [data-a-selector="value"]');
+        let quantity = parseInt(el.getAttribute('data-quantity'), 10);
+        if (isNaN(quantity)) quantity = box && box.value ? parseInt(box.value, 10) : number(prompt ? prompt.textContent : '1');
+
+        lines.push({
+            asin: asin,
+            title: title ? title.textContent.replace(/\s+/g, ' ').trim() : '',
+            unit_price: el.getAttribute('data-price') ? parseFloat(el.getAttribute('data-price')) : number(price ? price.textContent : ''),
+            quantity: quantity
+        });
+    }
+
+    const subtotal = document.querySelector('#sc-subtotal-amount-activecart .sc-price, #sc-subtotal-amount-buybox .sc-price, #sc-subtotal-amount-activecart');
+    const label = document.querySelector('#sc-subtotal-label-activecart, #sc-subtotal-label-buybox');
+    const count = (label ? label.textContent : '').match(/\((\d+)\s+items?\)/);
+    return {
+        lines: lines,
+        subtotal: number(subtotal ? subtotal.textContent : ''),
+        count: count ? parseInt(count[1], 10) : lines.reduce((n, l) => n + l.quantity, 0),
+        rendered: !!(subtotal || document.querySelector('#sc-active-cart, #activeCartViewForm'))
+    };
+}
+`
+
+// cartQuantityScript sets the quantity of one cart line through its
+// dropdown, or its quantity box for 10 and over, and says which it used:
+// "select", "box", "stepper" when the line only has +/- buttons, or "" when
+// it has no quantity control. Changes are made after the script returns,
+// so the evaluation isn't cut short by the page reloading.
+const cartQuantityScript = `
+(selector, asin, quantity) => {
+    const line = Array.from(document.querySelectorAll(selector)).find(el => el.getAttribute('data-asin') === asin);
+    if (!line) return '';
+    const select = line.querySelector('select[name="quantity"]');
+    if (select && Array.from(select.options).some(o => o.value === String(quantity))) {
+        setTimeout(() => {
+            select.value = String(quantity);
+            select.dispatchEvent(new Event('change', { bubbles: true }));
+        }, 0);
+        return 'select';
+    }
+    const box = line.querySelector('input[name="quantityBox"]');
+    const update = line.querySelector('[data-action="update"] a, [data-action="update"] input, .sc-update-link');
+    if (box && update) {
+        box.value = String(quantity);
+        box.dispatchEvent(new Event('input', { bubbles: true }));
+        setTimeout(() => update.click(), 0);
+        return 'box';
+    }
+    if (line.querySelector('[data-a-selector="increment"], [data-a-selector="decrement"]')) return 'stepper';
+    return '';
+}
+`
+
+// cartDeleteScript clicks the Delete control of one cart line.
+const cartDeleteScript = `
+(selector, asin) => {
+    const line = Array.from(document.querySelectorAll(selector)).find(el => el.getAttribute('data-asin') === asin);
+    if (!line) return false;
+    const del = line.querySelector('input[value="Delete"], [data-action="delete"] input, [data-action="delete"] a, .sc-action-delete input, .sc-action-delete a, [data-feature-id="delete"] input');
+    if (!del) return false;
+    setTimeout(() => del.click(), 0);
+    return true;
+}
+`
+
+// evaluateWith calls script with args, which are passed as JSON.
+func (e *Executor) evaluateWith(ctx context.Context, script string, args ...interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	return e.browser.Evaluate(ctx, fmt.Sprintf("(%s)(...%s)", strings.TrimSpace(script), encoded))
+}
+
+// readCart reads the current cart page.
+func (e *Executor) readCart(ctx context.Context) (*CartContents, error) {
+	raw, err := e.evaluateWith(ctx, cartScript, cartLineSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cart: %w", err)
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cart: %w", err)
+	}
+	var cart CartContents
+	if err := json.Unmarshal(encoded, &cart); err != nil {
+		return nil, fmt.Errorf("failed to read cart: %w", err)
+	}
+	return &cart, nil
+}
+
+// openCart goes to the cart page unless already there.
+func (e *Executor) openCart(ctx context.Context) error {
+	if isCartPage(e.browser.URL()) {
+		return nil
+	}
+	for _, selector := range []string{"#nav-cart", "#nav-cart-count-container", ".nav-cart-icon"} {
+		if e.browser.WaitForSelector(ctx, selector, 2*time.Second) != nil {
+			continue
+		}
+		if e.browser.Click(ctx, selector) == nil {
+			break
+		}
+	}
+	if err := e.browser.WaitForSelector(ctx, "#sc-active-cart, #activeCartViewForm", 10*time.Second); err != nil {
+		return fmt.Errorf("could not open the cart: %w", err)
+	}
+	return nil
+}
+
+func isCartPage(url string) bool {
+	return strings.Contains(url, "/cart") && !strings.Contains(url, "/cart/add")
+}
+
+// navCartCountScript reads the item count of the nav bar's cart link.
+const navCartCountScript = `
+() => {
+    const el = document.querySelector('#nav-cart-count');
+    const n = el ? parseInt(el.textContent.trim(), 10) : NaN;
+    return isNaN(n) ? -1 : n;
+}
+`
+
+// navCartCount returns the nav bar's cart count, or -1 when the page
+// shows none.
+func (e *Executor) navCartCount(ctx context.Context) int {
+	raw, err := e.evaluateWith(ctx, navCartCountScript)
+	if err != nil {
+		return -1
+	}
+	n, ok := raw.(float64)
+	if !ok {
+		return -1
+	}
+	return int(n)
+}
+
+// pendingAdd is an add_to_cart click and the nav cart count before it.
+type pendingAdd struct {
+	line      CartLine
+	cartCount int
+}
+
+// settlePendingAdd decides whether a failed add_to_cart step may be
+// retried. An attempt that fails after clicking (e.g. timing out while the
+// page loads) may still have added the product, and adding it again would
+// double the line, so the nav cart count is compared with the one before
+// the click. It returns nil, nil when the step may be retried; the step's
+// result when the product was added; and an error when it can't tell.
+func (e *Executor) settlePendingAdd(ctx context.Context) (*ExecutionResult, error) {
+	pending := e.pendingAdd
+	if pending == nil {
+		return nil, nil
+	}
+	after := e.navCartCount(ctx)
+	switch {
+	case pending.cartCount < 0 || after < 0:
+		return nil, fmt.Errorf("cannot tell from the cart count whether %s was added", pending.line)
+	case after == pending.cartCount:
+		return nil, nil
+	case after >= pending.cartCount+max(pending.line.Quantity, 1):
+		e.pendingAdd = nil
+		e.logf("   ✓ Added to cart: %s (by the failed attempt)\n", pending.line)
+		return &ExecutionResult{
+			Success: true,
+			Message: "Added to cart: " + pending.line.String(),
+			Data:    map[string]interface{}{"cart_line": pending.line},
+		}, nil
+	default:
+		return nil, fmt.Errorf("cart count went from %d to %d after adding %s", pending.cartCount, after, pending.line)
+	}
+}
+
+// waitForCart re-reads the cart page until done is satisfied by its lines,
+// for up to 10 seconds. Reads failing while the page reloads are retried,
+// and done only sees the lines of a page that has rendered, so a line
+// missing from a blank page doesn't count as removed.
+func (e *Executor) waitForCart(ctx context.Context, done func([]CartLine) bool) ([]CartLine, error) {
+	var cart *CartContents
+	var err error
+	for n := 0; n < 20; n++ {
+		if err := browser.Sleep(ctx, 500*time.Millisecond); err != nil {
+			return nil, err
+		}
+		if cart, err = e.readCart(ctx); err == nil && cart.Rendered && done(cart.Lines) {
+			return cart.Lines, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cart.Lines, fmt.Errorf("cart did not update")
+}
+
+// findCartLine finds the line item names: an ASIN, or words all found in
+// one line's title ("usb cable" for "Amazon Basics USB-C Cable 1m"). An
+// empty item names the only line of a one-line cart.
+func findCartLine(lines []CartLine, item string) (CartLine, error) {
+	item = strings.TrimSpace(item)
+	if len(lines) == 0 {
+		return CartLine{}, fmt.Errorf("the cart is empty")
+	}
+	if item == "" {
+		if len(lines) == 1 {
+			return lines[0], nil
+		}
+		return CartLine{}, fmt.Errorf("the cart has %d lines; say which item (%s)", len(lines), describeCart(lines))
+	}
+
+	for _, l := range lines {
+		if strings.EqualFold(l.ASIN, item) {
+			return l, nil
+		}
+	}
+	var found []CartLine
+	for _, l := range lines {
+		title := strings.ToLower(l.Title)
+		matches := true
+		for _, word := range strings.Fields(strings.ToLower(item)) {
+			if !strings.Contains(title, strings.TrimSuffix(word, "s")) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			found = append(found, l)
+		}
+	}
+	switch len(found) {
+	case 1:
+		return found[0], nil
+	case 0:
+		return CartLine{}, fmt.Errorf("no %q in the cart (%s)", item, describeCart(lines))
+	default:
+		return CartLine{}, fmt.Errorf("%q matches %d cart lines (%s)", item, len(found), describeCart(found))
+	}
+}
+
+func describeCart(lines []CartLine) string {
+	if len(lines) == 0 {
+		return "empty"
+	}
+	parts := make([]string, len(lines))
+	for i, l := range lines {
+		parts[i] = l.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// executeSetCartQuantity changes how many of one cart line to buy; 0
+// removes it.
+func (e *Executor) executeSetCartQuantity(ctx context.Context, step Step) (*ExecutionResult, error) {
+	quantity, ok := wholeNumber(step.Value)
+	if !ok {
+		return nil, fmt.Errorf("quantity must be a whole number, got %v", step.Value)
+	}
+	if quantity == 0 {
+		return e.removeCartLine(ctx, stringParam(step, "item"))
+	}
+
+	if err := e.openCart(ctx); err != nil {
+		return nil, err
+	}
+	cart, err := e.readCart(ctx)
+	if err != nil {
+		return nil, err
+	}
+	line, err := findCartLine(cart.Lines, stringParam(step, "item"))
+	if err != nil {
+		return nil, err
+	}
+	if line.Quantity == quantity {
+		e.logf("   🛒 Already %s\n", line)
+		return cartLineResult(line, fmt.Sprintf("Cart already has %s", line)), nil
+	}
+	if line, err = e.changeCartQuantity(ctx, line, quantity); err != nil {
+		return nil, err
+	}
+	e.logf("   ✓ Cart now has %s\n", line)
+	return cartLineResult(line, fmt.Sprintf("Cart now has %s", line)), nil
+}
+
+// changeCartQuantity sets the quantity of line on the cart page and
+// returns the line as the page then shows it.
+func (e *Executor) changeCartQuantity(ctx context.Context, line CartLine, quantity int) (CartLine, error) {
+	e.logf("   🛒 %s: quantity %d → %d\n", truncate(line.Title, 60), line.Quantity, quantity)
+	control, err := e.evaluateWith(ctx, cartQuantityScript, cartLineSelector, line.ASIN, quantity)
+	if err != nil {
+		return line, fmt.Errorf("failed to change quantity: %w", err)
+	}
+	switch control {
+	case "select", "box":
+	case "stepper":
+		if err := e.stepCartQuantity(ctx, line, quantity); err != nil {
+			return line, err
+		}
+	default:
+		return line, fmt.Errorf("no quantity control for %s in the cart", line.ASIN)
+	}
+
+	lines, err := e.waitForCart(ctx, func(lines []CartLine) bool {
+		l, err := findCartLine(lines, line.ASIN)
+		return err == nil && l.Quantity == quantity
+	})
+	if err != nil {
+		return line, fmt.Errorf("quantity of %s did not change to %d: %w", line.ASIN, quantity, err)
+	}
+	return findCartLine(lines, line.ASIN)
+}
+
+// stepCartQuantity clicks a line's +/- buttons until it has quantity.
+func (e *Executor) stepCartQuantity(ctx context.Context, line CartLine, quantity int) error {
+	current := line.Quantity
+	for n := 0; current != quantity && n < 30; n++ {
+		button := "increment"
+		if current > quantity {
+			button = "decrement"
+		}
+		selector := fmt.Sprintf(`#sc-active-cart [data-asin=%q] [data-a-selector=%q], #activeCartViewForm [data-asin=%q] [data-a-selector=%q]`,
+			line.ASIN, button, line.ASIN, button)
+		if err := e.browser.Click(ctx, selector); err != nil {
+			return fmt.Errorf("failed to change quantity: %w", err)
+		}
+		lines, err := e.waitForCart(ctx, func(lines []CartLine) bool {
+			l, err := findCartLine(lines, line.ASIN)
+			return err == nil && l.Quantity != current
+		})
+		if err != nil {
+			return fmt.Errorf("failed to change quantity: %w", err)
+		}
+		l, _ := findCartLine(lines, line.ASIN)
+		current = l.Quantity
+	}
+	return nil
+}
+
+// executeRemoveFromCart deletes one line from the cart.
+func (e *Executor) executeRemoveFromCart(ctx context.Context, step Step) (*ExecutionResult, error) {
+	item := step.GetValueString()
+	if item == "" {
+		item = stringParam(step, "item")
+	}
+	return e.removeCartLine(ctx, item)
+}
+
+func (e *Executor) removeCartLine(ctx context.Context, item string) (*ExecutionResult, error) {
+	if err := e.openCart(ctx); err != nil {
+		return nil, err
+	}
+	cart, err := e.readCart(ctx)
+	if err != nil {
+		return nil, err
+	}
+	line, err := findCartLine(cart.Lines, item)
+	if err != nil {
+		return nil, err
+	}
+
+	e.logf("   🛒 Removing %s\n", line)
+	clicked, err := e.evaluateWith(ctx, cartDeleteScript, cartLineSelector, line.ASIN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove %s: %w", line.ASIN, err)
+	}
+	if ok, _ := clicked.(bool); !ok {
+		return nil, fmt.Errorf("no Delete button for %s in the cart", line.ASIN)
+	}
+	if _, err := e.waitForCart(ctx, func(lines []CartLine) bool {
+		_, err := findCartLine(lines, line.ASIN)
+		return err != nil
+	}); err != nil {
+		return nil, fmt.Errorf("%s is still in the cart: %w", line.ASIN, err)
+	}
+
+	e.logf("   ✓ Removed from cart\n")
+	line.Quantity = 0
+	return cartLineResult(line, "Removed from cart: "+line.Title), nil
+}
+
+// cartLineResult reports the new state of one cart line, which memory
+// takes as the line's quantity.
+func cartLineResult(line CartLine, message string) *ExecutionResult {
+	return &ExecutionResult{
+		Success: true,
+		Message: message,
+		Data:    map[string]interface{}{"cart_set": line},
+	}
+}
+
+// cartMismatch is a difference between the cart lines a run added and the
+// cart page: Want is the quantity the run added (0 for a line it didn't
+// add) and Got the quantity in the cart (0 for a line missing from it).
+type cartMismatch struct {
+	Line      CartLine
+	Want, Got int
+}
+
+func (m cartMismatch) String() string {
+	title := m.Line.Title
+	if title == "" {
+		title = m.Line.ASIN
+	}
+	title = truncate(title, 60)
+	switch {
+	case m.Got == 0:
+		return fmt.Sprintf("%s is missing from the cart (want %d)", title, m.Want)
+	case m.Want == 0:
+		return fmt.Sprintf("%d × %s is in the cart but wasn't added by this task", m.Got, title)
+	default:
+		return fmt.Sprintf("%s: cart has %d, want %d", title, m.Got, m.Want)
+	}
+}
+
+// diffCart compares the intended cart lines with those in the cart, by
+// ASIN.
+func diffCart(intended, actual []CartLine) []cartMismatch {
+	var mismatches []cartMismatch
+	for _, want := range intended {
+		got := 0
+		if i := slices.IndexFunc(actual, func(l CartLine) bool { return l.ASIN == want.ASIN }); i >= 0 {
+			got = actual[i].Quantity
+		}
+		if got != want.Quantity {
+			mismatches = append(mismatches, cartMismatch{Line: want, Want: want.Quantity, Got: got})
+		}
+	}
+	for _, line := range actual {
+		if !slices.ContainsFunc(intended, func(l CartLine) bool { return l.ASIN == line.ASIN }) {
+			mismatches = append(mismatches, cartMismatch{Line: line, Got: line.Quantity})
+		}
+	}
+	return mismatches
+}
+
+// intendedCart is the cart lines memory says this run added, or false
+// when there are none to check the cart against.
+func (e *Executor) intendedCart() ([]CartLine, bool) {
+	intended := e.memory.CartItems
+	if len(intended) == 0 {
+		return nil, false
+	}
+	return intended, true
+}
+
+// logCart lists the cart's lines and subtotal, warning when the subtotal
+// doesn't add up from the lines.
+func (e *Executor) logCart(cart *CartContents) {
+	e.logf("   🛒 Cart: %d items, subtotal %s\n", cart.Count, formatRupees(cart.Subtotal))
+	total := 0.0
+	for _, line := range cart.Lines {
+		e.logf("      %s at %s\n", line, formatRupees(line.UnitPrice))
+		total += line.UnitPrice * float64(line.Quantity)
+	}
+	if cart.Subtotal > 0 && math.Abs(total-cart.Subtotal) >= 1 {
+		e.logf("   ⚠️  Subtotal %s doesn't match the lines (%s)\n", formatRupees(cart.Subtotal), formatRupees(total))
+	}
+}
+
+// executeReadCart reads the cart page into memory, noting how it differs
+// from the cart lines this run added.
+func (e *Executor) executeReadCart(ctx context.Context, step Step) (*ExecutionResult, error) {
+	if err := e.openCart(ctx); err != nil {
+		return nil, err
+	}
+	cart, err := e.readCart(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.logCart(cart)
+	if intended, ok := e.intendedCart(); ok {
+		for _, m := range diffCart(intended, cart.Lines) {
+			e.logf("   ⚠️  %s\n", m)
+		}
+	}
+
+	return &ExecutionResult{
+		Success: true,
+		Message: fmt.Sprintf("Cart has %d items, subtotal %s", cart.Count, formatRupees(cart.Subtotal)),
+		Data:    map[string]interface{}{"cart": cart},
+	}, nil
+}
+
+// reconcileCart checks the cart page against the cart lines this run
+// added before checkout. In repair mode it sets the quantities back and
+// removes lines the run didn't add; lines missing from the cart can't be
+// repaired from the cart page and fail the check, as any difference does
+// in fail mode.
+func (e *Executor) reconcileCart(ctx context.Context) (*CartContents, error) {
+	cart, err := e.readCart(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.logCart(cart)
+
+	intended, ok := e.intendedCart()
+	if !ok {
+		e.logf("   ⚠️  No cart lines recorded by this run; cart not checked\n")
+		return cart, nil
+	}
+	mismatches := diffCart(intended, cart.Lines)
+	if len(mismatches) == 0 {
+		e.logf("   ✓ Cart matches the items added\n")
+		return cart, nil
+	}
+	for _, m := range mismatches {
+		e.logf("   ⚠️  %s\n", m)
+	}
+	if e.cartReconcile == CartFail {
+		return nil, fmt.Errorf("cart does not match the items added: %s", describeMismatches(mismatches))
+	}
+
+	for _, m := range mismatches {
+		if m.Got == 0 {
+			return nil, fmt.Errorf("cannot repair cart: %s", m)
+		}
+	}
+	for _, m := range mismatches {
+		switch {
+		case m.Want == 0:
+			if _, err := e.removeCartLine(ctx, m.Line.ASIN); err != nil {
+				return nil, fmt.Errorf("cannot repair cart: %w", err)
+			}
+		default:
+			line := m.Line
+			line.Quantity = m.Got
+			if _, err := e.changeCartQuantity(ctx, line, m.Want); err != nil {
+				return nil, fmt.Errorf("cannot repair cart: %w", err)
+			}
+		}
+	}
+
+	if cart, err = e.readCart(ctx); err != nil {
+		return nil, err
+	}
+	if mismatches := diffCart(intended, cart.Lines); len(mismatches) > 0 {
+		return nil, fmt.Errorf("cart still does not match after repair: %s", describeMismatches(mismatches))
+	}
+	e.logf("   🔧 Cart repaired: %s, subtotal %s\n", describeCart(cart.Lines), formatRupees(cart.Subtotal))
+	return cart, nil
+}
+
+func describeMismatches(mismatches []cartMismatch) string {
+	parts := make([]string, len(mismatches))
+	for i, m := range mismatches {
+		parts[i] = m.String()
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,183 @@
+package amazon_agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Checkpoint statuses.
+const (
+	CheckpointRunning     = "running"
+	CheckpointCompleted   = "completed"
+	CheckpointFailed      = "failed"
+	CheckpointInterrupted = "interrupted"
+)
+
+const (
+	checkpointFile   = "checkpoint.json"
+	storageStateFile = "storage_state.json"
+)
+
+// Checkpoint is everything needed to resume a run: the current plan, where
+// to continue in it, what has run so far, the agent memory and the browser
+// session (saved next to the checkpoint as Playwright storage state).
+type Checkpoint struct {
+	RunID           string         `json:"run_id"`
+	Status          string         `json:"status"`
+	TaskDescription string         `json:"task"`
+	Plan            *Plan          `json:"plan"`
+	NextStep        int            `json:"next_step"`
+	ExecutedSteps   []ExecutedStep `json:"executed_steps"`
+	Memory          *AgentMemory   `json:"memory"`
+	CurrentURL      string         `json:"current_url,omitempty"`
+	StorageState    string         `json:"storage_state,omitempty"`
+	Session         string         `json:"session,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// LoadCheckpoint reads the checkpoint of runID from runsDir.
+func LoadCheckpoint(runsDir, runID string) (*Checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(runsDir, runID, checkpointFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no checkpoint for run %s in %s", runID, runsDir)
+		}
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("parse checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+func newRunID() string {
+	buf := make([]byte, 3)
+	rand.Read(buf)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(buf)
+}
+
+func (a *Agent) startRun(runID string) error {
+	a.runID = runID
+	a.runDir = filepath.Join(a.config.RunsDir, runID)
+	a.checkpoint = nil
+	a.recoveries = nil
+	if err := os.MkdirAll(a.runDir, 0o755); err != nil {
+		return fmt.Errorf("create run directory: %w", err)
+	}
+	a.openEventLog()
+	a.browser.SetOutputDir(a.runDir)
+	return nil
+}
+
+// saveCheckpoint persists the run state. Failures are reported but never
+// stop the run. It deliberately ignores the run's context so an interrupted
+// run still records where it stopped.
+func (a *Agent) saveCheckpoint(executionContext *ExecutionContext, nextStep int, status string) {
+	if a.runDir == "" {
+		return
+	}
+
+	now := time.Now()
+	if a.checkpoint == nil {
+		a.checkpoint = &Checkpoint{RunID: a.runID, CreatedAt: now}
+	}
+	cp := a.checkpoint
+	cp.Status = status
+	cp.TaskDescription = executionContext.TaskDescription
+	cp.Plan = executionContext.Plan
+	cp.NextStep = nextStep
+	cp.ExecutedSteps = executionContext.ExecutedSteps
+	cp.Memory = a.memory
+	cp.Session = a.config.Session
+	cp.UpdatedAt = now
+
+	a.writeCheckpoint()
+}
+
+func (a *Agent) updateCheckpointStatus(status string) {
+	if a.checkpoint == nil {
+		return
+	}
+	a.checkpoint.Status = status
+	a.checkpoint.UpdatedAt = time.Now()
+	a.writeCheckpoint()
+}
+
+func (a *Agent) writeCheckpoint() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cp := a.checkpoint
+	cp.CurrentURL = a.browser.URL()
+	if err := a.browser.SaveStorageState(ctx, filepath.Join(a.runDir, storageStateFile)); err != nil {
+		a.logf("   ⚠️  Could not save browser session: %v\n", err)
+	} else {
+		cp.StorageState = storageStateFile
+	}
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		a.logf("   ⚠️  Could not encode checkpoint: %v\n", err)
+		return
+	}
+
+	// Write then rename so a crash mid-write never corrupts the checkpoint.
+	path := filepath.Join(a.runDir, checkpointFile)
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		a.logf("   ⚠️  Could not save checkpoint: %v\n", err)
+		return
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		a.logf("   ⚠️  Could not save checkpoint: %v\n", err)
+	}
+}
+
+type executedStepJSON struct {
+	Step      Step       `json:"step"`
+	Success   bool       `json:"success"`
+	Error     string     `json:"error,omitempty"`
+	Timestamp time.Time  `json:"timestamp"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	Retries   int        `json:"retries,omitempty"`
+}
+
+func (s ExecutedStep) MarshalJSON() ([]byte, error) {
+	out := executedStepJSON{
+		Step:      s.Step,
+		Success:   s.Success,
+		Timestamp: s.Timestamp,
+		Artifacts: s.Artifacts,
+		Retries:   s.Retries,
+	}
+	if s.Error != nil {
+		out.Error = s.Error.Error()
+	}
+	return json.Marshal(out)
+}
+
+func (s *ExecutedStep) UnmarshalJSON(data []byte) error {
+	var in executedStepJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	s.Step = in.Step
+	s.Success = in.Success
+	s.Timestamp = in.Timestamp
+	s.Artifacts = in.Artifacts
+	s.Retries = in.Retries
+	s.Error = nil
+	if in.Error != "" {
+		s.Error = errors.New(in.Error)
+	}
+	return nil
+}
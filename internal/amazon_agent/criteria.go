@@ -0,0 +1,311 @@
+package amazon_agent
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Sort keys of ProductCriteria.
+const (
+	SortRelevance = ""
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortRating    = "rating"
+	SortReviews   = "reviews"
+	// SortNewest keeps page order: results carry no dates, so it picks the
+	// newest only on results sort_results ordered by Newest Arrivals.
+	SortNewest = "newest"
+)
+
+// ProductCriteria is the filter-and-rank spec select_product compiles its
+// criteria into. Zero fields don't filter.
+type ProductCriteria struct {
+	MinPrice        float64  `json:"min_price,omitempty"`
+	MaxPrice        float64  `json:"max_price,omitempty"`
+	MinRating       float64  `json:"min_rating,omitempty"`
+	MinReviews      int      `json:"min_reviews,omitempty"`
+	Brands          []string `json:"brands,omitempty"`
+	ExcludeBrands   []string `json:"exclude_brands,omitempty"`
+	Keywords        []string `json:"keywords,omitempty"`
+	ExcludeKeywords []string `json:"exclude_keywords,omitempty"`
+	PrimeOnly       bool     `json:"prime_only,omitempty"`
+	NonSponsored    bool     `json:"non_sponsored,omitempty"`
+	Sort            string   `json:"sort,omitempty"`
+	// Position is the 1-based rank to pick among the matching results;
+	// zero means the first.
+	Position int `json:"position,omitempty"`
+	// Ignored are the words of the criteria the parser didn't understand.
+	Ignored []string `json:"ignored,omitempty"`
+}
+
+// criteriaPrice matches an amount such as "₹1,499", "rs 500" or "2k".
+const criteriaPrice = `(?:₹|rs\.?|inr)?\s*(\d[\d,]*(?:\.\d+)?)\s*(k\b)?`
+
+// criteriaRule recognizes one phrase of a criteria string. Rules run in
+// order over what earlier rules left, so more specific phrases go first.
+type criteriaRule struct {
+	pattern *regexp.Regexp
+	apply   func(c *ProductCriteria, m []string)
+}
+
+func rule(pattern string, apply func(c *ProductCriteria, m []string)) criteriaRule {
+	return criteriaRule{pattern: regexp.MustCompile(`(?i)` + pattern), apply: apply}
+}
+
+func sortRule(pattern, key string) criteriaRule {
+	return rule(pattern, func(c *ProductCriteria, _ []string) { c.Sort = key })
+}
+
+var criteriaRules = []criteriaRule{
+	rule(`\b(?:non|not|no|un|exclude|excluding|skip|without)[\s-]*sponsored\b|\borganic\b`, func(c *ProductCriteria, _ []string) {
+		c.NonSponsored = true
+	}),
+	rule(`\b(?:only\s+)?prime(?:[\s-]+(?:only|eligible))?\b`, func(c *ProductCriteria, _ []string) {
+		c.PrimeOnly = true
+	}),
+
+	sortRule(`\bsort(?:ed)?\s+by\s+price(?:\s*(?::|,)?\s*(?:high\s+to\s+low|descending))\b|\bmost\s+expensive\b|\bpriciest\b|\bhighest\s+price\b|\bprice\s+high\s+to\s+low\b`, SortPriceDesc),
+	sortRule(`\bsort(?:ed)?\s+by\s+price(?:\s*(?::|,)?\s*(?:low\s+to\s+high|ascending))?\b|\bcheapest\b|\bleast\s+expensive\b|\blowest\s+price[ds]?\b|\bprice\s+low\s+to\s+high\b`, SortPriceAsc),
+	sortRule(`\bsort(?:ed)?\s+by\s+(?:ratings?|stars|(?:(?:avg\.?|average)\s+)?customer\s+(?:reviews?|ratings?)|(?:avg\.?|average)\s+(?:reviews?|ratings?))\b|\b(?:highest|best|top)[\s-]+(?:rated|rating|reviewed)\b|\bbest\b`, SortRating),
+	sortRule(`\bsort(?:ed)?\s+by\s+(?:(?:number\s+of\s+)?reviews|popularity)\b|\bmost\s+(?:reviewed|reviews|ratings)\b|\b(?:most\s+)?popular\b`, SortReviews),
+	sortRule(`\b(?:sort(?:ed)?\s+)?by\s+(?:newest|latest|date)(?:\s+(?:first|arrivals?))?\b|\b(?:newest|latest)(?:\s+(?:first|arrivals?))?\b|\bnew\s+arrivals\b`, SortNewest),
+	sortRule(`\b(?:sort(?:ed)?\s+)?by\s+(?:relevance|featured)\b|\bmost\s+relevant\b`, SortRelevance),
+
+	rule(`(?:\b(?:at\s+least|over|more\s+than|above|min(?:imum)?(?:\s+of)?)\s*|>=?\s*)?(\d[\d,]*)\s*(k\b)?\s*\+?\s*(?:customer\s+)?(?:reviews|ratings)\b|\b(?:reviews|ratings)\s*(?:above|over|at\s+least|more\s+than|>=?)\s*(\d[\d,]*)\s*(k\b)?`, func(c *ProductCriteria, m []string) {
+		c.MinReviews = int(parseAmount(m[1]+m[3], m[2]+m[4]))
+	}),
+	rule(`\b(?:rating|rated|stars?)\s*(?:of\s+|is\s+)?(?:above|over|at\s+least|more\s+than|>=?|of)?\s*(\d(?:\.\d+)?)\s*\+?(?:\s*stars?)?|\b(\d(?:\.\d+)?)\s*\+?\s*(?:-\s*)?(?:stars?|star\s+rating|rated|rating)\b(?:\s*(?:\band\b|\bor\b|&)\s*(?:above|up|more|higher)\b)?`, func(c *ProductCriteria, m []string) {
+		c.MinRating, _ = strconv.ParseFloat(m[1]+m[2], 64)
+	}),
+
+	rule(`\b(?:between|from)\s+`+criteriaPrice+`\s*(?:and|to|-)\s*`+criteriaPrice+`|`+`(?:₹|rs\.?|inr)\s*(\d[\d,]*(?:\.\d+)?)\s*(k\b)?\s*(?:-|to)\s*`+criteriaPrice, func(c *ProductCriteria, m []string) {
+		c.MinPrice = parseAmount(m[1]+m[5], m[2]+m[6])
+		c.MaxPrice = parseAmount(m[3]+m[7], m[4]+m[8])
+	}),
+	rule(`(?:\b(?:under|below|less\s+than|no\s+more\s+than|not\s+more\s+than|cheaper\s+than|at\s+most|max(?:imum)?(?:\s+price)?(?:\s+of)?|up\s*to|within|budget(?:\s+of)?)\s*|<=?\s*)`+criteriaPrice, func(c *ProductCriteria, m []string) {
+		c.MaxPrice = parseAmount(m[1], m[2])
+	}),
+	rule(`(?:\b(?:over|above|more\s+than|at\s+least|min(?:imum)?(?:\s+price)?(?:\s+of)?|costlier\s+than)\s*|>=?\s*)`+criteriaPrice, func(c *ProductCriteria, m []string) {
+		// A bare "above 4" is about the rating, not the price.
+		if v := parseAmount(m[1], m[2]); v <= 5 && !strings.ContainsAny(m[0], "₹") && !strings.Contains(strings.ToLower(m[0]), "rs") {
+			c.MinRating = v
+		} else {
+			c.MinPrice = v
+		}
+	}),
+
+	rule(`"([^"]+)"`, func(c *ProductCriteria, m []string) {
+		c.Keywords = append(c.Keywords, m[1])
+	}),
+	rule(`\b(?:containing|mentioning|with\s+the\s+words?|keywords?|titled)\s+([\w-]+)`, func(c *ProductCriteria, m []string) {
+		c.Keywords = append(c.Keywords, m[1])
+	}),
+	rule(`\bwithout\s+([\w-]+)`, func(c *ProductCriteria, m []string) {
+		c.ExcludeKeywords = append(c.ExcludeKeywords, m[1])
+	}),
+	// Conditions are words of the title, not brands.
+	rule(`\b(?:not|no|non|except|excluding|exclude|avoid)[\s-]+(refurbished|renewed|used|pre-owned|second[\s-]hand|open[\s-]box)\b`, func(c *ProductCriteria, m []string) {
+		c.ExcludeKeywords = append(c.ExcludeKeywords, m[1])
+	}),
+	rule(`\b(?:not|no|except|excluding|exclude|avoid)\s+(?:brands?\s+)?([\w&'.-]+(?:\s*(?:/|\bor\b|\bnor\b)\s*[\w&'.-]+)*)`, func(c *ProductCriteria, m []string) {
+		c.ExcludeBrands = append(c.ExcludeBrands, splitBrands(m[1])...)
+	}),
+	rule(`\b(?:brands?(?:\s*(?:is|:|=))?|by|from|made\s+by)\s+([\w&'.-]+(?:\s*(?:/|\bor\b)\s*[\w&'.-]+)*)|\b([\w&'.-]+)\s+brand\b`, func(c *ProductCriteria, m []string) {
+		c.Brands = append(c.Brands, splitBrands(m[1]+m[2])...)
+	}),
+
+	rule(`\b(?:first|1st|top)\b`, func(c *ProductCriteria, _ []string) { c.Position = 1 }),
+	rule(`\b(?:second|2nd)\b`, func(c *ProductCriteria, _ []string) { c.Position = 2 }),
+	rule(`\b(?:third|3rd)\b`, func(c *ProductCriteria, _ []string) { c.Position = 3 }),
+	rule(`\b(\d+)(?:st|nd|rd|th)\b`, func(c *ProductCriteria, m []string) {
+		c.Position, _ = strconv.Atoi(m[1])
+	}),
+}
+
+// criteriaFiller are the words left over from a criteria string that don't
+// mean anything on their own.
+var criteriaFiller = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true, "with": true,
+	"only": true, "one": true, "product": true, "item": true, "result": true, "results": true,
+	"that": true, "is": true, "has": true, "have": true, "of": true, "price": true, "priced": true,
+	"rated": true, "rating": true, "select": true, "choose": true, "pick": true, "good": true,
+	"available": true, "option": true, "listed": true, "rupees": true, "inr": true, "₹": true,
+}
+
+// notBrands are words that follow "by" or "from" without naming a brand:
+// orders, the parts of a review, and when to deliver.
+var notBrands = map[string]bool{
+	"relevance": true, "featured": true, "default": true, "popularity": true, "newest": true,
+	"latest": true, "date": true, "prices": true, "ratings": true, "stars": true, "review": true,
+	"reviews": true, "customer": true, "customers": true, "avg": true, "avg.": true, "average": true,
+	"today": true, "tomorrow": true, "page": true, "search": true, "amazon": true,
+}
+
+// isBrand reports whether word may name a brand: it is neither filler nor
+// one of notBrands.
+func isBrand(word string) bool {
+	word = strings.ToLower(word)
+	return !criteriaFiller[word] && !notBrands[word]
+}
+
+// ParseProductCriteria compiles natural-language criteria such as "rating
+// above 4 and under 2000, not sponsored, Prime only" into a
+// ProductCriteria. It doesn't fail: words it can't place are listed in
+// Ignored so the run log shows them.
+func ParseProductCriteria(criteria string) *ProductCriteria {
+	c := &ProductCriteria{}
+	rest := criteria
+	for _, r := range criteriaRules {
+		rest = r.pattern.ReplaceAllStringFunc(rest, func(match string) string {
+			r.apply(c, r.pattern.FindStringSubmatch(match))
+			return " , "
+		})
+	}
+	for _, word := range strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ',' || r == ';' || r == '&' || r == '.' || r == '!' || r == '?' || r == ' ' || r == '\t' || r == '\n'
+	}) {
+		if !criteriaFiller[strings.ToLower(word)] {
+			c.Ignored = append(c.Ignored, word)
+		}
+	}
+	return c
+}
+
+// parseAmount reads a number such as "1,499" with an optional "k" suffix.
+func parseAmount(number, suffix string) float64 {
+	v, _ := strconv.ParseFloat(strings.ReplaceAll(number, ",", ""), 64)
+	if suffix != "" {
+		v *= 1000
+	}
+	return v
+}
+
+var brandSeparator = regexp.MustCompile(`(?i)\s*(?:/|\bor\b|\bnor\b)\s*`)
+
+// splitBrands splits "Sony or JBL" into its brands, dropping words that
+// aren't, such as the "the" of "from the results".
+func splitBrands(s string) []string {
+	var brands []string
+	for _, brand := range brandSeparator.Split(s, -1) {
+		if brand = strings.TrimSpace(brand); brand != "" && isBrand(brand) {
+			brands = append(brands, brand)
+		}
+	}
+	return brands
+}
+
+// Matches reports whether r passes every filter of c.
+func (c *ProductCriteria) Matches(r SearchResult) bool {
+	switch {
+	case c.MinPrice > 0 && r.Price < c.MinPrice,
+		c.MaxPrice > 0 && (r.Price == 0 || r.Price > c.MaxPrice),
+		c.MinRating > 0 && r.Rating < c.MinRating,
+		c.MinReviews > 0 && r.Reviews < c.MinReviews,
+		c.PrimeOnly && !r.Prime,
+		c.NonSponsored && r.Sponsored:
+		return false
+	}
+	if len(c.Brands) > 0 && !containsFunc(c.Brands, r.hasBrand) {
+		return false
+	}
+	if containsFunc(c.ExcludeBrands, r.hasBrand) {
+		return false
+	}
+	title := strings.ToLower(r.Title)
+	for _, keyword := range c.Keywords {
+		if !strings.Contains(title, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	for _, keyword := range c.ExcludeKeywords {
+		if strings.Contains(title, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasBrand reports whether r is of brand: its brand line if the page
+// shows one, else the start of its title.
+func (r SearchResult) hasBrand(brand string) bool {
+	if r.Brand != "" {
+		return strings.EqualFold(r.Brand, brand)
+	}
+	title, brand := strings.ToLower(r.Title), strings.ToLower(brand)
+	return title == brand || strings.HasPrefix(title, brand+" ")
+}
+
+func containsFunc(values []string, f func(string) bool) bool {
+	for _, v := range values {
+		if f(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders c for the run log, e.g. "₹500–₹2000, rating ≥ 4, Prime,
+// not sponsored, sorted by price (low to high)".
+func (c *ProductCriteria) String() string {
+	var parts []string
+	if price := c.priceRange(); price != "" {
+		parts = append(parts, price)
+	}
+	if c.MinRating > 0 {
+		parts = append(parts, fmt.Sprintf("rating ≥ %g", c.MinRating))
+	}
+	if c.MinReviews > 0 {
+		parts = append(parts, fmt.Sprintf("≥ %d reviews", c.MinReviews))
+	}
+	if len(c.Brands) > 0 {
+		parts = append(parts, "brand "+strings.Join(c.Brands, "/"))
+	}
+	if len(c.ExcludeBrands) > 0 {
+		parts = append(parts, "not "+strings.Join(c.ExcludeBrands, "/"))
+	}
+	for _, keyword := range c.Keywords {
+		parts = append(parts, fmt.Sprintf("title has %q", keyword))
+	}
+	for _, keyword := range c.ExcludeKeywords {
+		parts = append(parts, fmt.Sprintf("title lacks %q", keyword))
+	}
+	if c.PrimeOnly {
+		parts = append(parts, "Prime")
+	}
+	if c.NonSponsored {
+		parts = append(parts, "not sponsored")
+	}
+	if c.Sort != SortRelevance {
+		parts = append(parts, "sorted by "+sortDescriptions[c.Sort])
+	}
+	if c.Position > 1 {
+		parts = append(parts, fmt.Sprintf("pick #%d", c.Position))
+	}
+	if len(parts) == 0 {
+		return "first result"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// priceRange describes the price bounds of c, or is empty if it has none.
+func (c *ProductCriteria) priceRange() string {
+	switch {
+	case c.MinPrice > 0 && c.MaxPrice > 0:
+		return formatRupees(c.MinPrice) + "–" + formatRupees(c.MaxPrice)
+	case c.MaxPrice > 0:
+		return "price ≤ " + formatRupees(c.MaxPrice)
+	case c.MinPrice > 0:
+		return "price ≥ " + formatRupees(c.MinPrice)
+	}
+	return ""
+}
+
+var sortDescriptions = map[string]string{
+	SortPriceAsc:  "price (low to high)",
+	SortPriceDesc: "price (high to low)",
+	SortRating:    "rating",
+	SortReviews:   "reviews",
+	SortNewest:    "newest (page order)",
+}
@@ -0,0 +1,55 @@
+package amazon_agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProductCriteria(t *testing.T) {
+	tests := []struct {
+		criteria string
+		want     ProductCriteria
+	}{
+		{"cheapest", ProductCriteria{Sort: SortPriceAsc}},
+		{"most expensive under ₹2,000", ProductCriteria{MaxPrice: 2000, Sort: SortPriceDesc}},
+		{"between 500 and 1.5k", ProductCriteria{MinPrice: 500, MaxPrice: 1500}},
+		{"rating above 4 and under 2000, not sponsored, Prime only", ProductCriteria{
+			MaxPrice: 2000, MinRating: 4, PrimeOnly: true, NonSponsored: true,
+		}},
+		{"4 stars & up with at least 1k reviews", ProductCriteria{MinRating: 4, MinReviews: 1000}},
+		{"second cheapest", ProductCriteria{Sort: SortPriceAsc, Position: 2}},
+
+		// Orders introduced by "by" are sorts, not brands.
+		{"sort by customer reviews", ProductCriteria{Sort: SortRating}},
+		{"sort by avg. customer review", ProductCriteria{Sort: SortRating}},
+		{"sort by reviews", ProductCriteria{Sort: SortReviews}},
+		{"sort by newest", ProductCriteria{Sort: SortNewest}},
+		{"newest arrivals under 5000", ProductCriteria{MaxPrice: 5000, Sort: SortNewest}},
+		{"by relevance", ProductCriteria{}},
+		{"cheapest, sorted by relevance", ProductCriteria{}},
+		{"by price", ProductCriteria{}},
+
+		// Brands.
+		{"by Sony", ProductCriteria{Brands: []string{"Sony"}}},
+		{"from Samsung or LG", ProductCriteria{Brands: []string{"Samsung", "LG"}}},
+		{"boAt brand, cheapest", ProductCriteria{Brands: []string{"boAt"}, Sort: SortPriceAsc}},
+		{"not JBL", ProductCriteria{ExcludeBrands: []string{"JBL"}}},
+		{"the first from the results", ProductCriteria{Position: 1}},
+
+		// Conditions and keywords are about the title.
+		{"exclude refurbished", ProductCriteria{ExcludeKeywords: []string{"refurbished"}}},
+		{"no renewed, by Apple", ProductCriteria{Brands: []string{"Apple"}, ExcludeKeywords: []string{"renewed"}}},
+		{`"noise cancelling", without wired`, ProductCriteria{
+			Keywords: []string{"noise cancelling"}, ExcludeKeywords: []string{"wired"},
+		}},
+
+		{"something shiny", ProductCriteria{Ignored: []string{"something", "shiny"}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.criteria, func(t *testing.T) {
+			if got := ParseProductCriteria(tc.criteria); !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
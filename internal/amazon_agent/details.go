@@ -0,0 +1,157 @@
+package amazon_agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// ProductDetails is what extract_product reads from a product page.
+type ProductDetails struct {
+	ASIN  string `json:"asin"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// Price and MRP are in rupees, and Discount the percentage off the
+	// MRP; each is zero when the page doesn't show it.
+	Price    float64 `json:"price,omitempty"`
+	MRP      float64 `json:"mrp,omitempty"`
+	Discount int     `json:"discount,omitempty"`
+	// Rating is out of 5.
+	Rating  float64 `json:"rating,omitempty"`
+	Reviews int     `json:"reviews,omitempty"`
+	Seller  string  `json:"seller,omitempty"`
+	// Delivery is the delivery estimate as shown, like "FREE delivery
+	// Tomorrow", and Availability the stock line, like "In stock".
+	Delivery     string   `json:"delivery,omitempty"`
+	Availability string   `json:"availability,omitempty"`
+	Bullets      []string `json:"bullets,omitempty"`
+	Images       []string `json:"images,omitempty"`
+}
+
+// productDetailsScript reads a product page, covering the older layouts
+// (#priceblock_ourprice, #merchant-info) as well as the current one.
+const productDetailsScript = `
+() => {
+    const number = (text) => {
+        const m = (text || '').replace(/,/g, '').match(/\d+(\.\d+)?/);
+        return m ? parseFloat(m[0]) : 0;
+    };
+    const count = (text) => {
+        const m = (text || '').replace(/,/g, '').match(/(\d+(\.\d+)?)\s*([kKmM])?/);
+        if (!m) return 0;
+        const scale = { k: 1e3, m: 1e6 }[(m[3] || '').toLowerCase()] || 1;
+        return Math.round(parseFloat(m[1]) * scale);
+    };
+    const clean = (text) => (text || '').replace(/\s+/g, ' ').trim();
+    const text = (selectors) => {
+        const el = document.querySelector(selectors);
+        return el ? clean(el.innerText || el.textContent) : '';
+    };
+    // Thumbnail URLs carry a size modifier ("._SS40_") before the
+    // extension; without it they give the full image.
+    const fullImage = (src) => (src || '').replace(/\._[^/]*_\.(jpg|jpeg|png|webp)$/i, '.$1');
+
+    const asinInput = document.querySelector('input#ASIN, input[name="ASIN"]');
+    const dp = document.querySelector('#dp[data-asin]');
+    const m = location.href.match(/\/(?:dp|gp\/product)\/([A-Z0-9]{10})/);
+
+    const rating = document.querySelector('#acrPopover, #averageCustomerReviews .a-icon-alt');
+    const seller = text('#sellerProfileTriggerId, #merchantInfoFeature_feature_div .offer-display-feature-text-message, #merchant-info a, #merchant-info');
+
+    const images = [];
+    const addImage = (src) => {
+        if (!src || src.startsWith('data:')) return;
+        src = new URL(fullImage(src), location.href).href;
+        if (!images.includes(src)) images.push(src);
+    };
+    const landing = document.querySelector('#landingImage, #imgBlkFront, #main-image');
+    if (landing) addImage(landing.getAttribute('data-old-hires') || landing.getAttribute('src'));
+    for (const img of document.querySelectorAll('#altImages li.imageThumbnail img, #altImages .item img')) {
+        addImage(img.getAttribute('src'));
+    }
+
+    const bullets = [];
+    for (const li of document.querySelectorAll('#feature-bullets li, #featurebullets_feature_div li')) {
+        const t = clean(li.innerText || li.textContent);
+        if (t && !/^see more product details/i.test(t)) bullets.push(t);
+    }
+
+    return {
+        asin: (asinInput && asinInput.value) || (dp && dp.getAttribute('data-asin')) || (m ? m[1] : ''),
+        title: text('#productTitle, #title'),
+        url: location.href,
+        price: number(text('#corePrice_feature_div .a-price:not(.a-text-price) .a-offscreen, #corePriceDisplay_desktop_feature_div .a-price:not(.a-text-price) .a-offscreen, #priceblock_ourprice, #priceblock_dealprice')),
+        mrp: number(text('#corePrice_feature_div .a-text-price[data-a-strike="true"] .a-offscreen, #corePriceDisplay_desktop_feature_div .basisPrice .a-offscreen, .priceBlockStrikePriceString')),
+        discount: Math.abs(number(text('#corePrice_feature_div .savingsPercentage, #corePriceDisplay_desktop_feature_div .savingsPercentage'))),
+        rating: rating ? number(rating.getAttribute('title') || rating.textContent) : 0,
+        reviews: count(text('#acrCustomerReviewText')),
+        seller: seller.replace(/^sold by\s*/i, ''),
+        delivery: text('#mir-layout-DELIVERY_BLOCK-slot-PRIMARY_DELIVERY_MESSAGE_LARGE, #deliveryBlockMessage, #delivery-message'),
+        availability: text('#availability'),
+        bullets: bullets,
+        images: images
+    };
+}
+`
+
+// extractProductDetails reads the current product page.
+func (e *Executor) extractProductDetails(ctx context.Context) (*ProductDetails, error) {
+	raw, err := e.browser.Evaluate(ctx, productDetailsScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract product details: %w", err)
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract product details: %w", err)
+	}
+	var d ProductDetails
+	if err := json.Unmarshal(encoded, &d); err != nil {
+		return nil, fmt.Errorf("failed to extract product details: %w", err)
+	}
+	if d.Title == "" {
+		return nil, fmt.Errorf("not a product page: no product title on %s", d.URL)
+	}
+	if d.Discount == 0 && d.MRP > d.Price && d.Price > 0 {
+		d.Discount = int(math.Round((d.MRP - d.Price) / d.MRP * 100))
+	}
+	return &d, nil
+}
+
+// executeExtractProduct reads the details of the current product page
+// into memory.
+func (e *Executor) executeExtractProduct(ctx context.Context, step Step) (*ExecutionResult, error) {
+	d, err := e.extractProductDetails(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.logf("   📋 %s (%s)\n", truncate(d.Title, 60), d.ASIN)
+	price := "no price shown"
+	if d.Price > 0 {
+		price = formatRupees(d.Price)
+		if d.MRP > d.Price {
+			price += fmt.Sprintf(" (MRP %s, %d%% off)", formatRupees(d.MRP), d.Discount)
+		}
+	}
+	e.logf("      Price: %s\n", price)
+	if d.Rating > 0 {
+		e.logf("      Rating: %.1f★ from %d reviews\n", d.Rating, d.Reviews)
+	}
+	for _, field := range []struct{ label, value string }{
+		{"Seller", d.Seller},
+		{"Delivery", d.Delivery},
+		{"Availability", d.Availability},
+	} {
+		if field.value != "" {
+			e.logf("      %s: %s\n", field.label, field.value)
+		}
+	}
+	e.logf("      %d bullets, %d images\n", len(d.Bullets), len(d.Images))
+
+	return &ExecutionResult{
+		Success: true,
+		Message: fmt.Sprintf("Extracted details of %s", d.Title),
+		Data:    map[string]interface{}{"product_details": d},
+	}, nil
+}
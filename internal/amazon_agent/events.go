@@ -0,0 +1,252 @@
+package amazon_agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventTaskStarted  EventType = "task_started"
+	EventPlanCreated  EventType = "plan_created"
+	EventStepStarted  EventType = "step_started"
+	EventStepFinished EventType = "step_finished"
+	EventValidation   EventType = "validation"
+	EventReplan       EventType = "replan"
+	EventRecovery     EventType = "recovery"
+	EventTaskDone     EventType = "task_done"
+	// EventMessage carries free-form progress text from actions, such as
+	// "Email entered" during login.
+	EventMessage EventType = "message"
+)
+
+// Event is one entry of a run's event stream. Type selects which of the
+// payload fields is set.
+type Event struct {
+	Type  EventType `json:"type"`
+	Time  time.Time `json:"time"`
+	RunID string    `json:"run_id,omitempty"`
+
+	Task       *TaskEvent       `json:"task,omitempty"`
+	Plan       *Plan            `json:"plan,omitempty"`
+	Step       *StepEvent       `json:"step,omitempty"`
+	Validation *ValidationEvent `json:"validation,omitempty"`
+	Replan     *ReplanEvent     `json:"replan,omitempty"`
+	Recovery   *RecoveryAttempt `json:"recovery,omitempty"`
+	Result     *ResultEvent     `json:"result,omitempty"`
+	Message    string           `json:"message,omitempty"`
+}
+
+// TaskEvent describes the start of a run. Resumed runs start at StartStep
+// (0-based) of the checkpointed plan.
+type TaskEvent struct {
+	Description   string `json:"description"`
+	RunDir        string `json:"run_dir,omitempty"`
+	Resumed       bool   `json:"resumed,omitempty"`
+	StartStep     int    `json:"start_step,omitempty"`
+	TotalSteps    int    `json:"total_steps,omitempty"`
+	StepsExecuted int    `json:"steps_executed,omitempty"`
+}
+
+// StepEvent describes a step attempt. Number is 1-based within the current
+// plan; Attempt is 1 for the first attempt and counts up through the
+// retries of a failed critical step.
+type StepEvent struct {
+	Number   int           `json:"number"`
+	Total    int           `json:"total"`
+	Step     Step          `json:"step"`
+	Attempt  int           `json:"attempt,omitempty"`
+	Success  bool          `json:"success"`
+	Stopped  bool          `json:"stopped,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns,omitempty"`
+	// Artifacts are the snapshots taken for this attempt.
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+}
+
+type ValidationEvent struct {
+	IsComplete      bool    `json:"is_complete"`
+	NeedsReplanning bool    `json:"needs_replanning"`
+	Message         string  `json:"message,omitempty"`
+	Confidence      float64 `json:"confidence"`
+	Phase           string  `json:"phase,omitempty"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// ReplanEvent describes a replan. Plan is nil when the planner failed and
+// the run carried on with its current plan.
+type ReplanEvent struct {
+	Reason string `json:"reason"`
+	Plan   *Plan  `json:"plan,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ResultEvent struct {
+	Success       bool          `json:"success"`
+	Interrupted   bool          `json:"interrupted,omitempty"`
+	StepsExecuted int           `json:"steps_executed"`
+	Duration      time.Duration `json:"duration_ns"`
+	FinalState    string        `json:"final_state,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	// Recoveries counts the recovery attempts; each was published as a
+	// recovery event.
+	Recoveries int `json:"recoveries,omitempty"`
+	// Output is the task's structured answer, if its plan has an output
+	// schema.
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// Observer receives every event of a run, in order, on the goroutine that
+// runs the task.
+type Observer interface {
+	OnEvent(Event)
+}
+
+type ObserverFunc func(Event)
+
+func (f ObserverFunc) OnEvent(e Event) { f(e) }
+
+// MultiObserver fans events out to several observers.
+func MultiObserver(observers ...Observer) Observer {
+	return ObserverFunc(func(e Event) {
+		for _, o := range observers {
+			o.OnEvent(e)
+		}
+	})
+}
+
+// ConsoleRenderer prints events as the agent's human-readable progress
+// output.
+type ConsoleRenderer struct {
+	w      io.Writer
+	runDir string
+}
+
+func NewConsoleRenderer(w io.Writer) *ConsoleRenderer {
+	return &ConsoleRenderer{w: w}
+}
+
+func (r *ConsoleRenderer) OnEvent(e Event) {
+	switch e.Type {
+	case EventTaskStarted:
+		r.runDir = e.Task.RunDir
+		if t := e.Task; t.Resumed {
+			fmt.Fprintf(r.w, "♻️  Resuming run %s at step %d/%d (%d steps already executed)\n\n",
+				e.RunID, t.StartStep+1, t.TotalSteps, t.StepsExecuted)
+		}
+	case EventPlanCreated:
+		fmt.Fprintf(r.w, "📋 Generated plan with %d initial steps\n\n", len(e.Plan.Steps))
+	case EventStepStarted:
+		fmt.Fprintf(r.w, "🔄 Step %d/%d: %s\n", e.Step.Number, e.Step.Total, e.Step.Step.Description)
+	case EventStepFinished:
+		s := e.Step
+		switch {
+		case s.Stopped:
+			fmt.Fprintf(r.w, "   ⏹️  Stopped: %s\n", s.Error)
+		case s.Success && s.Attempt > 1:
+			fmt.Fprintf(r.w, "   ✓ Retry successful\n")
+		case s.Success:
+			fmt.Fprintf(r.w, "   ✓ Completed\n")
+		case s.Attempt <= 1:
+			fmt.Fprintf(r.w, "   ❌ Failed: %s\n", s.Error)
+		default:
+			fmt.Fprintf(r.w, "   ❌ Retry failed: %s\n", s.Error)
+		}
+		if !s.Success {
+			for _, artifact := range s.Artifacts {
+				if artifact.Label != ArtifactBefore && artifact.Screenshot != "" {
+					fmt.Fprintf(r.w, "   📸 Snapshot: %s\n", filepath.Join(r.runDir, artifact.Screenshot))
+				}
+			}
+		}
+	case EventValidation:
+		v := e.Validation
+		if v.Error != "" {
+			fmt.Fprintf(r.w, "   ⚠️  Validation error: %s\n", v.Error)
+			return
+		}
+		if v.Phase != "" {
+			fmt.Fprintf(r.w, "   📍 Current phase: %s\n", v.Phase)
+		}
+		if v.IsComplete {
+			fmt.Fprintf(r.w, "\n🎉 Task completed: %s\n", v.Message)
+		} else if v.NeedsReplanning {
+			fmt.Fprintf(r.w, "   🔄 Replanning required: %s\n", v.Message)
+		}
+	case EventReplan:
+		if e.Replan.Plan == nil {
+			fmt.Fprintf(r.w, "   ⚠️  Replan failed: %s, continuing with original plan\n", e.Replan.Error)
+		} else {
+			fmt.Fprintf(r.w, "   📋 New plan with %d steps\n", len(e.Replan.Plan.Steps))
+		}
+	case EventRecovery:
+		rec := e.Recovery
+		switch {
+		case rec.Success && rec.Plan != nil:
+			fmt.Fprintf(r.w, "   📋 Recovery plan with %d steps\n", len(rec.Plan.Steps))
+		case rec.Success:
+			fmt.Fprintf(r.w, "   🩹 Recovered (%s): %s\n", rec.Strategy, rec.Detail)
+		case rec.Error != "":
+			fmt.Fprintf(r.w, "   ⚠️  Recovery %s failed: %s\n", rec.Strategy, rec.Error)
+		default:
+			fmt.Fprintf(r.w, "   ⚠️  Recovery %s: %s\n", rec.Strategy, rec.Detail)
+		}
+	case EventMessage:
+		fmt.Fprint(r.w, e.Message)
+	}
+}
+
+// JSONLSink writes each event as one JSON object per line. Message text is
+// trimmed of the console indentation.
+type JSONLSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{enc: json.NewEncoder(w)}
+}
+
+func (s *JSONLSink) OnEvent(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.Message = strings.TrimSpace(e.Message)
+	s.enc.Encode(e)
+}
+
+// emit stamps e with the time and run ID, hands it to the observer and
+// appends it to the run's events.jsonl.
+func (a *Agent) emit(e Event) {
+	e.Time = time.Now()
+	e.RunID = a.runID
+	if a.observer != nil {
+		a.observer.OnEvent(e)
+	}
+	if a.eventSink != nil {
+		a.eventSink.OnEvent(e)
+	}
+}
+
+func (a *Agent) logf(format string, args ...interface{}) {
+	a.emit(Event{Type: EventMessage, Message: fmt.Sprintf(format, args...)})
+}
+
+// SetObserver replaces the observer receiving the run's events; by default
+// they are rendered to stdout by a ConsoleRenderer.
+func (a *Agent) SetObserver(o Observer) {
+	a.observer = o
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
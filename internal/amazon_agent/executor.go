@@ -1,22 +1,41 @@
 package amazon_agent
 
 import (
-	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
-	"os"
+	"slices"
+	"strconv"
 	"strings"
-	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"browser-agent/internal/browser"
 	"browser-agent/internal/llm"
-	"golang.org/x/term"
 )
 
 type Executor struct {
-	browser *browser.Browser
-	llm     *llm.GeminiClient
-	memory  *AgentMemory
+	browser  *browser.Browser
+	llm      llm.Client
+	memory   *AgentMemory
+	prompter Prompter
+	actions  *ActionRegistry
+	// smartActionConfidence is the confidence a smart_action suggestion
+	// needs before it is carried out.
+	smartActionConfidence float64
+	// searchPages and searchLimit bound the results pages read and the
+	// products collected from them by select_product and collect_products.
+	searchPages int
+	searchLimit int
+	// cartReconcile is what proceed_checkout does when the cart page
+	// differs from the cart lines added: CartRepair, CartFail or
+	// CartReconcileOff.
+	cartReconcile string
+	// pendingAdd is the add_to_cart click made by the last step, if any;
+	// see settlePendingAdd.
+	pendingAdd *pendingAdd
+	// emit publishes progress messages; nil prints them to stdout.
+	emit func(Event)
 }
 
 type ExecutionResult struct {
@@ -26,95 +45,120 @@ type ExecutionResult struct {
 	NextStep *Step
 }
 
-func NewExecutor(br *browser.Browser, llmClient *llm.GeminiClient, memory *AgentMemory) *Executor {
+const (
+	defaultSmartActionConfidence = 0.6
+	defaultSearchPages           = 3
+	defaultSearchLimit           = 60
+)
+
+func NewExecutor(br *browser.Browser, llmClient llm.Client, memory *AgentMemory) *Executor {
+	actions := NewActionRegistry()
+	for _, action := range builtinActions() {
+		if err := actions.Register(action); err != nil {
+			panic(err)
+		}
+	}
 	return &Executor{
-		browser: br,
-		llm:     llmClient,
-		memory:  memory,
+		browser:  br,
+		llm:      llmClient,
+		memory:   memory,
+		prompter: NewTerminalPrompter(),
+		actions:  actions,
+
+		smartActionConfidence: defaultSmartActionConfidence,
+		searchPages:           defaultSearchPages,
+		searchLimit:           defaultSearchLimit,
+		cartReconcile:         CartFail,
 	}
 }
 
-func (e *Executor) executeGoBack(step Step) (*ExecutionResult, error) {
-    fmt.Printf("   ↩️  Going back to previous page\n")
-    
-    // Method 1: Use JavaScript history.back()
-    _, err := e.browser.Evaluate("window.history.back()")
-    if err != nil {
-        return nil, fmt.Errorf("history.back() failed: %w", err)
-    }
-    
-    // Wait for page to load
-    time.Sleep(3 * time.Second)
-    
-    // Method 2: Try to verify we moved
-    pageState, _ := e.browser.GetPageState()
-    fmt.Printf("   📍 Now at: %s\n", pageState.Title[:min(50, len(pageState.Title))])
-    
-    // Check if we're back on search results
-    if strings.Contains(pageState.URL, "/s?k=") || 
-       strings.Contains(pageState.URL, "/s?field-keywords=") {
-        fmt.Printf("   ✓ Successfully returned to search results\n")
-    }
-    
-    return &ExecutionResult{
-        Success: true,
-        Message: "Navigated back successfully",
-    }, nil
+// Actions is the registry ExecuteStep dispatches through. Actions
+// registered on it are also offered to the planner.
+func (e *Executor) Actions() *ActionRegistry {
+	return e.actions
 }
 
-func (e *Executor) ExecuteStep(step Step, ctx *ExecutionContext) (*ExecutionResult, error) {
-	if step.Action == "type" && strings.Contains(strings.ToLower(step.Description), "search") {
-		return e.executeDynamicSearch(step, ctx)
-	}
-
-	switch step.Action {
-	case "navigate":
-		return e.executeNavigate(step)
-	case "click":
-		return e.executeClick(step)
-	case "type":
-		return e.executeType(step)
-	case "wait":
-		return e.executeWait(step)
-	case "extract":
-		return e.executeExtract(step)
-	case "verify":
-		return e.executeVerify(step)
-	case "request_auth", "request_credentials", "login":
-		return e.executeRequestAuth(step)
-	case "scroll":
-		return e.executeScroll(step)
-	case "select_product":
-		return e.executeSelectProduct(step, ctx)
-	case "add_to_cart":
-		return e.executeAddToCart(step)
-	case "proceed_checkout":
-		return e.executeProceedCheckout(step)
-	case "fill_address":
-		return e.executeFillAddress(step)
-	case "select_payment":
-		return e.executeSelectPayment(step)
-	case "smart_action":
-		return e.executeSmartAction(step, ctx)
-	case "go_back", "back":
-		return e.executeGoBack(step)
-	default:
-		fmt.Printf("   ⚠️  Unknown action '%s', trying smart fallback...\n", step.Action)
-		return e.executeSmartAction(step, ctx)
+// Browser is the browser the executor drives, for use by actions.
+func (e *Executor) Browser() *browser.Browser {
+	return e.browser
+}
+
+// Memory is the agent memory actions record their findings in.
+func (e *Executor) Memory() *AgentMemory {
+	return e.memory
+}
+
+// Logf publishes a progress message, like the built-in actions do.
+func (e *Executor) Logf(format string, args ...interface{}) {
+	e.logf(format, args...)
+}
+
+func (e *Executor) executeGoBack(ctx context.Context, step Step) (*ExecutionResult, error) {
+	e.logf("   ↩️  Going back to previous page\n")
+
+	// Method 1: Use JavaScript history.back()
+	_, err := e.browser.Evaluate(ctx, "window.history.back()")
+	if err != nil {
+		return nil, fmt.Errorf("history.back() failed: %w", err)
+	}
+
+	// Wait for page to load
+	browser.Sleep(ctx, 3*time.Second)
+
+	// Method 2: Try to verify we moved
+	pageState := e.currentPage(ctx)
+	e.logf("   📍 Now at: %s\n", truncate(pageState.Title, 50))
+
+	// Check if we're back on search results
+	if strings.Contains(pageState.URL, "/s?k=") ||
+		strings.Contains(pageState.URL, "/s?field-keywords=") {
+		e.logf("   ✓ Successfully returned to search results\n")
 	}
+
+	return &ExecutionResult{
+		Success: true,
+		Message: "Navigated back successfully",
+	}, nil
 }
 
-func (e *Executor) executeNavigate(step Step) (*ExecutionResult, error) {
+func (e *Executor) logf(format string, args ...interface{}) {
+	if e.emit == nil {
+		fmt.Printf(format, args...)
+		return
+	}
+	e.emit(Event{Type: EventMessage, Message: fmt.Sprintf(format, args...)})
+}
+
+// ExecuteStep runs one plan step. Cancelling ctx aborts the step and its
+// error is ctx.Err(), even if the action swallowed a browser error.
+func (e *Executor) ExecuteStep(ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+	e.pendingAdd = nil
+	result, err := e.dispatch(ctx, step, execCtx)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return result, err
+}
+
+func (e *Executor) dispatch(ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+	action, ok := e.actions.Lookup(step.Action)
+	if !ok {
+		return nil, fmt.Errorf("unknown action %q", step.Action)
+	}
+	return action.Execute(ctx, e, step, execCtx)
+}
+
+func (e *Executor) executeNavigate(ctx context.Context, step Step) (*ExecutionResult, error) {
 	if step.Target == "" {
 		return nil, fmt.Errorf("navigate requires target URL")
 	}
 
-	err := e.browser.Navigate(step.Target)
+	err := e.browser.Navigate(ctx, step.Target)
 	if err != nil {
 		return nil, fmt.Errorf("navigate to %s: %w", step.Target, err)
 	}
 
-	pageState, _ := e.browser.GetPageState()
+	pageState := e.currentPage(ctx)
 	return &ExecutionResult{
 		Success: true,
 		Message: fmt.Sprintf("Navigated to %s", step.Target),
@@ -122,26 +166,26 @@ func (e *Executor) executeNavigate(step Step) (*ExecutionResult, error) {
 	}, nil
 }
 
-func (e *Executor) executeClick(step Step) (*ExecutionResult, error) {
+func (e *Executor) executeClick(ctx context.Context, step Step) (*ExecutionResult, error) {
 	if step.Target == "" {
 		return nil, fmt.Errorf("click requires target selector")
 	}
 
-	err := e.browser.WaitForSelector(step.Target, 5*time.Second)
+	err := e.browser.WaitForSelector(ctx, step.Target, 5*time.Second)
 	if err != nil {
 		if strings.Contains(strings.ToLower(step.Description), "first") {
-			return e.clickFirstItem(step)
+			return e.clickFirstItem(ctx, step)
 		}
-		pageState, _ := e.browser.GetPageState()
-		return e.findAndClickAlternative(step, pageState)
+		pageState := e.currentPage(ctx)
+		return e.findAndClickAlternative(ctx, step, pageState)
 	}
 
-	err = e.browser.Click(step.Target)
+	err = e.browser.Click(ctx, step.Target)
 	if err != nil {
 		return nil, fmt.Errorf("click %s: %w", step.Target, err)
 	}
 
-	time.Sleep(1 * time.Second)
+	browser.Sleep(ctx, 1*time.Second)
 
 	return &ExecutionResult{
 		Success: true,
@@ -149,9 +193,18 @@ func (e *Executor) executeClick(step Step) (*ExecutionResult, error) {
 	}, nil
 }
 
-func (e *Executor) executeScroll(step Step) (*ExecutionResult, error) {
-	direction := "down"
-	amount := 500
+func (e *Executor) executeScroll(ctx context.Context, step Step) (*ExecutionResult, error) {
+	direction := strings.ToLower(stringParam(step, "direction"))
+	if direction == "" {
+		direction = "down"
+	}
+	amount, ok, err := intParam(step, "amount")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		amount = 500
+	}
 
 	if step.Parameters != nil {
 		if submitVal, ok := step.Parameters["submit"]; ok {
@@ -160,13 +213,13 @@ func (e *Executor) executeScroll(step Step) (*ExecutionResult, error) {
 			case bool:
 				if v {
 					// Submit is true
-					time.Sleep(500 * time.Millisecond)
-					e.browser.Press(step.Target, "Enter")
+					browser.Sleep(ctx, 500*time.Millisecond)
+					e.browser.Press(ctx, step.Target, "Enter")
 				}
 			case string:
 				if v == "true" {
-					time.Sleep(500 * time.Millisecond)
-					e.browser.Press(step.Target, "Enter")
+					browser.Sleep(ctx, 500*time.Millisecond)
+					e.browser.Press(ctx, step.Target, "Enter")
 				}
 			}
 		}
@@ -183,15 +236,14 @@ func (e *Executor) executeScroll(step Step) (*ExecutionResult, error) {
 	case "top":
 		script = "window.scrollTo(0, 0)"
 	default:
-		script = fmt.Sprintf("window.scrollBy(0, %d)", amount)
+		return nil, fmt.Errorf("unknown scroll direction %q (want up, down, top or bottom)", direction)
 	}
 
-	_, err := e.browser.Evaluate(script)
-	if err != nil {
+	if _, err := e.browser.Evaluate(ctx, script); err != nil {
 		return nil, fmt.Errorf("scroll failed: %w", err)
 	}
 
-	time.Sleep(1 * time.Second)
+	browser.Sleep(ctx, 1*time.Second)
 
 	return &ExecutionResult{
 		Success: true,
@@ -199,195 +251,197 @@ func (e *Executor) executeScroll(step Step) (*ExecutionResult, error) {
 	}, nil
 }
 
-func (e *Executor) executeSelectProduct(step Step, ctx *ExecutionContext) (*ExecutionResult, error) {
-    criteria := step.GetValueString()
-    if criteria == "" && step.Parameters != nil {
-        if crit, ok := step.Parameters["criteria"]; ok {
-            if critStr, ok := crit.(string); ok {
-                criteria = critStr
-            }
-        }
-    }
-
-    // **OVERRIDE**: If criteria contains "rating above 4", "best-rated", etc., ignore it
-    // Just select first product
-    if strings.Contains(strings.ToLower(criteria), "rating") || 
-       strings.Contains(strings.ToLower(criteria), "best") ||
-       strings.Contains(strings.ToLower(criteria), "above") {
-        fmt.Printf("   ⚠️  Ignoring rating criteria, selecting first available product\n")
-        criteria = "first"
-    }
-
-    fmt.Printf("   🔍 Selecting product based on: %s\n", criteria)
-
-    // Get all product links
-    script := `
-    () => {
-        const links = [];
-        // Try multiple selectors
-        const selectors = [
-            '[data-component-type="s-search-result"] a[href*="/dp/"]',
-            '.s-result-item a[href*="/dp/"]',
-            'div[data-asin] h2 a',
-            'a[href*="/dp/"]'
-        ];
-        
-        for (let selector of selectors) {
-            const elements = document.querySelectorAll(selector);
-            if (elements.length > 0) {
-                elements.forEach((el, idx) => {
-                    if (el.href && el.href.includes('/dp/')) {
-                        links.push({
-                            index: idx,
-                            href: el.href,
-                            title: el.innerText || el.textContent || el.getAttribute('title') || ''
-                        });
-                    }
-                });
-                break;
-            }
-        }
-        
-        return links.slice(0, 20); // Limit to first 20
-    }
-    `
-
-    result, err := e.browser.Evaluate(script)
-    if err != nil {
-        return nil, fmt.Errorf("failed to extract products: %w", err)
-    }
-
-    links, ok := result.([]interface{})
-    if !ok || len(links) == 0 {
-        return nil, fmt.Errorf("no products found on page")
-    }
-
-    // Always select the first product regardless of criteria
-    selectedIndex := 0
-    
-    if selectedIndex < len(links) {
-        linkData := links[selectedIndex].(map[string]interface{})
-        href := linkData["href"].(string)
-        title := linkData["title"].(string)
-        
-        // Clean up title
-        title = strings.TrimSpace(title)
-        if title == "" {
-            title = "Product"
-        }
-        
-        fmt.Printf("   ✓ Selected product: %s\n", title[:min(60, len(title))])
-        
-        // Navigate to product
-        err = e.browser.Navigate(href)
-        if err != nil {
-            return nil, fmt.Errorf("failed to navigate to product: %w", err)
-        }
-        
-        // Wait longer for product page
-        time.Sleep(4 * time.Second)
-        
-        // Check if we're on product page
-        pageState, _ := e.browser.GetPageState()
-        fmt.Printf("   📍 Current URL: %s\n", pageState.URL)
-        
-        // Verify we're on product page
-        if !strings.Contains(pageState.URL, "/dp/") && 
-           !strings.Contains(pageState.URL, "/gp/product/") {
-            fmt.Printf("   ⚠️  Warning: May not be on product page\n")
-            // Continue anyway
-        }
-        
-        return &ExecutionResult{
-            Success: true,
-            Message: fmt.Sprintf("Selected product: %s", title),
-            Data: map[string]interface{}{
-                "selected_product": title,
-                "product_url":      pageState.URL,
-            },
-        }, nil
-    }
-
-    return nil, fmt.Errorf("could not select product")
+// stringParam reads a string step parameter, "" if it is missing.
+func stringParam(step Step, name string) string {
+	s, _ := step.Parameters[name].(string)
+	return strings.TrimSpace(s)
 }
 
-func (e *Executor) selectProductByCriteria(products []interface{}, criteria string) int {
-	criteriaLower := strings.ToLower(criteria)
-	
-	// Default to first product if no criteria
-	if criteriaLower == "" {
-		return 0
-	}
-	
-	if strings.Contains(criteriaLower, "first") || strings.Contains(criteriaLower, "1st") {
-		return 0
-	}
-	
-	if strings.Contains(criteriaLower, "second") || strings.Contains(criteriaLower, "2nd") {
-		if len(products) > 1 {
-			return 1
-		}
+// intParam reads a positive whole-number step parameter, which plans give
+// as a number or a string.
+func intParam(step Step, name string) (int, bool, error) {
+	value, ok := step.Parameters[name]
+	if !ok {
+		return 0, false, nil
 	}
-	
-	if strings.Contains(criteriaLower, "third") || strings.Contains(criteriaLower, "3rd") {
-		if len(products) > 2 {
-			return 2
-		}
+	n, ok := wholeNumber(value)
+	if !ok || n < 1 {
+		return 0, false, fmt.Errorf("parameter %s must be a whole number of at least 1, got %v", name, value)
 	}
-	
-	// Select by rating (good rating = 4.0+)
-	if strings.Contains(criteriaLower, "rating") || strings.Contains(criteriaLower, "rated") || 
-	   strings.Contains(criteriaLower, "stars") || strings.Contains(criteriaLower, "star") {
-		maxRating := 0.0
-		maxIndex := 0
-		for i, p := range products {
-			product := p.(map[string]interface{})
-			rating := 0.0
-			
-			// Handle both float64 and string ratings
-			switch r := product["rating"].(type) {
-			case float64:
-				rating = r
-			case string:
-				fmt.Sscanf(r, "%f", &rating)
-			}
-			
-			if rating >= 4.0 && rating > maxRating {
-				maxRating = rating
-				maxIndex = i
-			}
-		}
-		if maxRating >= 4.0 {
-			return maxIndex
-		}
-		return 0 // Fallback to first if no 4+ rating found
-	}
-	
-	// Select cheapest
-	if strings.Contains(criteriaLower, "cheap") || strings.Contains(criteriaLower, "low price") || 
-	   strings.Contains(criteriaLower, "lowest") {
-		minPrice := -1.0
-		minIndex := 0
-		for i, p := range products {
-			product := p.(map[string]interface{})
-			priceStr := product["price"].(string)
-			var price float64
-			fmt.Sscanf(priceStr, "%f", &price)
-			if price > 0 && (minPrice < 0 || price < minPrice) {
-				minPrice = price
-				minIndex = i
+	return n, true, nil
+}
+
+// wholeNumber reads a non-negative whole number given as a number or a
+// string.
+func wholeNumber(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		return int(v), v >= 0 && v == float64(int(v))
+	case int:
+		return v, v >= 0
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		return n, err == nil && n >= 0
+	}
+	return 0, false
+}
+
+func (e *Executor) executeSelectProduct(ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+	criteria := step.GetValueString()
+	if criteria == "" && step.Parameters != nil {
+		if crit, ok := step.Parameters["criteria"]; ok {
+			if critStr, ok := crit.(string); ok {
+				criteria = critStr
 			}
 		}
-		if minPrice > 0 {
-			return minIndex
+	}
+
+	e.logf("   🔍 Selecting product based on: %s\n", criteria)
+	spec := ParseProductCriteria(criteria)
+	e.logf("   🧾 Parsed criteria: %s\n", spec)
+	if len(spec.Ignored) > 0 {
+		e.logf("   ⚠️  Not understood, ignored: %s\n", strings.Join(spec.Ignored, " "))
+	}
+
+	pages, limit, err := e.searchBounds(step)
+	if err != nil {
+		return nil, err
+	}
+	// Picking by page order needs no more pages once enough results
+	// match; any other ranking looks at every page allowed.
+	var enough func([]SearchResult) bool
+	if spec.Sort == SortRelevance || spec.Sort == SortNewest {
+		enough = func(results []SearchResult) bool {
+			return len(rankResults(results, spec)) >= max(spec.Position, 1)
 		}
 	}
-	
-	// Default to first product
-	return 0
+	results, err := e.collectSearchResults(ctx, pages, limit, enough)
+	if err != nil {
+		return nil, err
+	}
+	e.logf("   📦 Found %d products\n", len(results))
+
+	selectedIndex, reason, err := selectProductByCriteria(results, spec)
+	if err != nil {
+		return nil, err
+	}
+	selected := results[selectedIndex]
+
+	title := selected.Title
+	if title == "" {
+		title = "Product"
+	}
+	e.logf("   ✓ Selected product #%d: %s\n", selected.Position, truncate(title, 60))
+	e.logf("   🎯 Why: %s\n", reason)
+
+	// Navigate to product
+	err = e.browser.Navigate(ctx, selected.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to navigate to product: %w", err)
+	}
+
+	// Wait longer for product page
+	browser.Sleep(ctx, 4*time.Second)
+
+	// Check if we're on product page
+	pageState := e.currentPage(ctx)
+	e.logf("   📍 Current URL: %s\n", pageState.URL)
+
+	// Verify we're on product page
+	if !strings.Contains(pageState.URL, "/dp/") &&
+		!strings.Contains(pageState.URL, "/gp/product/") {
+		e.logf("   ⚠️  Warning: May not be on product page\n")
+		// Continue anyway
+	}
+
+	return &ExecutionResult{
+		Success: true,
+		Message: fmt.Sprintf("Selected product: %s (%s)", title, reason),
+		Data: map[string]interface{}{
+			"selected_product": title,
+			"product_url":      pageState.URL,
+			"asin":             selected.ASIN,
+			"price":            selected.Price,
+			"rating":           selected.Rating,
+			"selection_reason": reason,
+			"criteria":         spec,
+		},
+	}, nil
+}
+
+// executeCollectProducts gathers the products of the search results pages
+// that match the step's criteria, best first, into memory for later steps.
+func (e *Executor) executeCollectProducts(ctx context.Context, step Step) (*ExecutionResult, error) {
+	spec := ParseProductCriteria(step.GetValueString())
+	e.logf("   🧾 Parsed criteria: %s\n", spec)
+	if len(spec.Ignored) > 0 {
+		e.logf("   ⚠️  Not understood, ignored: %s\n", strings.Join(spec.Ignored, " "))
+	}
+
+	pages, limit, err := e.searchBounds(step)
+	if err != nil {
+		return nil, err
+	}
+	results, err := e.collectSearchResults(ctx, pages, limit, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no products found on page")
+	}
+
+	var products []SearchResult
+	for _, i := range rankResults(results, spec) {
+		products = append(products, results[i])
+	}
+	e.logf("   📦 Collected %d products, %d matching\n", len(results), len(products))
+	for i, p := range products[:min(5, len(products))] {
+		e.logf("      %d. %s (%s)\n", i+1, truncate(p.Title, 60), describeResult(p))
+	}
+
+	return &ExecutionResult{
+		Success: true,
+		Message: fmt.Sprintf("Collected %d products matching %s", len(products), spec),
+		Data: map[string]interface{}{
+			"products": products,
+			"criteria": spec,
+		},
+	}, nil
 }
 
-func (e *Executor) executeAddToCart(step Step) (*ExecutionResult, error) {
+// executeAddToCart adds the current product to the cart, in the quantity
+// the step's "quantity" parameter asks for (1 by default).
+func (e *Executor) executeAddToCart(ctx context.Context, step Step) (*ExecutionResult, error) {
+	quantity, ok, err := intParam(step, "quantity")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		quantity = 1
+	}
+
+	raw, err := e.evaluateWith(ctx, productLineScript, quantity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read product: %w", err)
+	}
+	var product struct {
+		Line CartLine `json:"line"`
+		Set  bool     `json:"set"`
+		Max  int      `json:"max"`
+	}
+	encoded, err := json.Marshal(raw)
+	if err == nil {
+		err = json.Unmarshal(encoded, &product)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read product: %w", err)
+	}
+	if !product.Set {
+		return nil, fmt.Errorf("cannot add %d: the product page offers up to %d (set the rest with set_cart_quantity)", quantity, product.Max)
+	}
+	line := product.Line
+	countBefore := e.navCartCount(ctx)
+
 	addToCartSelectors := []string{
 		"#add-to-cart-button",
 		"input[name='submit.add-to-cart']",
@@ -397,18 +451,19 @@ func (e *Executor) executeAddToCart(step Step) (*ExecutionResult, error) {
 	}
 
 	for _, selector := range addToCartSelectors {
-		err := e.browser.WaitForSelector(selector, 3*time.Second)
+		err := e.browser.WaitForSelector(ctx, selector, 3*time.Second)
 		if err == nil {
-			err = e.browser.Click(selector)
+			e.pendingAdd = &pendingAdd{line: line, cartCount: countBefore}
+			err = e.browser.Click(ctx, selector)
 			if err == nil {
-				time.Sleep(2 * time.Second)
-				
-				fmt.Printf("   ✓ Added to cart\n")
-				
+				browser.Sleep(ctx, 2*time.Second)
+
+				e.logf("   ✓ Added to cart: %s\n", line)
+
 				return &ExecutionResult{
 					Success: true,
-					Message: "Product added to cart",
-					Data:    map[string]interface{}{"cart_item": "added"},
+					Message: "Added to cart: " + line.String(),
+					Data:    map[string]interface{}{"cart_line": line},
 				}, nil
 			}
 		}
@@ -417,7 +472,7 @@ func (e *Executor) executeAddToCart(step Step) (*ExecutionResult, error) {
 	return nil, fmt.Errorf("could not find add to cart button")
 }
 
-func (e *Executor) executeProceedCheckout(step Step) (*ExecutionResult, error) {
+func (e *Executor) executeProceedCheckout(ctx context.Context, step Step) (*ExecutionResult, error) {
 	checkoutSelectors := []string{
 		"#sc-buy-box-ptc-button",
 		"[name='proceedToRetailCheckout']",
@@ -434,11 +489,11 @@ func (e *Executor) executeProceedCheckout(step Step) (*ExecutionResult, error) {
 
 	cartOpened := false
 	for _, selector := range cartSelectors {
-		err := e.browser.WaitForSelector(selector, 2*time.Second)
+		err := e.browser.WaitForSelector(ctx, selector, 2*time.Second)
 		if err == nil {
-			err = e.browser.Click(selector)
+			err = e.browser.Click(ctx, selector)
 			if err == nil {
-				time.Sleep(2 * time.Second)
+				browser.Sleep(ctx, 2*time.Second)
 				cartOpened = true
 				break
 			}
@@ -446,18 +501,32 @@ func (e *Executor) executeProceedCheckout(step Step) (*ExecutionResult, error) {
 	}
 
 	if !cartOpened {
-		fmt.Printf("   ⚠️  Could not open cart, trying direct checkout\n")
+		e.logf("   ⚠️  Could not open cart, trying direct checkout\n")
+	}
+
+	data := map[string]interface{}{}
+	if e.cartReconcile != CartReconcileOff {
+		if isCartPage(e.browser.URL()) {
+			cart, err := e.reconcileCart(ctx)
+			if err != nil {
+				return nil, err
+			}
+			data["cart"] = cart
+		} else if cartOpened {
+			e.logf("   ⚠️  Not on the cart page; cart not checked\n")
+		}
 	}
 
 	for _, selector := range checkoutSelectors {
-		err := e.browser.WaitForSelector(selector, 3*time.Second)
+		err := e.browser.WaitForSelector(ctx, selector, 3*time.Second)
 		if err == nil {
-			err = e.browser.Click(selector)
+			err = e.browser.Click(ctx, selector)
 			if err == nil {
-				time.Sleep(3 * time.Second)
+				browser.Sleep(ctx, 3*time.Second)
 				return &ExecutionResult{
 					Success: true,
 					Message: "Proceeding to checkout",
+					Data:    data,
 				}, nil
 			}
 		}
@@ -466,11 +535,9 @@ func (e *Executor) executeProceedCheckout(step Step) (*ExecutionResult, error) {
 	return nil, fmt.Errorf("could not find proceed to checkout button")
 }
 
-func (e *Executor) executeFillAddress(step Step) (*ExecutionResult, error) {
-	reader := bufio.NewReader(os.Stdin)
-	
-	fmt.Printf("\n📍 Shipping Address Required\n")
-	
+func (e *Executor) executeFillAddress(ctx context.Context, step Step) (*ExecutionResult, error) {
+	e.logf("\n📍 Shipping Address Required\n")
+
 	fields := []struct {
 		name     string
 		selector string
@@ -484,91 +551,92 @@ func (e *Executor) executeFillAddress(step Step) (*ExecutionResult, error) {
 		{"city", "#address-ui-widgets-enterAddressCity", "City: "},
 		{"state", "#address-ui-widgets-enterAddressStateOrRegion", "State: "},
 	}
-	
+
 	for _, field := range fields {
-		err := e.browser.WaitForSelector(field.selector, 2*time.Second)
+		err := e.browser.WaitForSelector(ctx, field.selector, 2*time.Second)
 		if err != nil {
 			continue
 		}
-		
-		fmt.Print(field.prompt)
-		input, _ := reader.ReadString('\n')
-		value := strings.TrimSpace(input)
-		
+
+		value, err := e.prompter.Prompt(ctx, field.name, field.prompt)
+		if err != nil {
+			return nil, err
+		}
+
 		if value != "" {
-			err = e.browser.Type(field.selector, value)
+			err = e.browser.Type(ctx, field.selector, value)
 			if err != nil {
-				fmt.Printf("   ⚠️  Could not fill %s\n", field.name)
+				e.logf("   ⚠️  Could not fill %s\n", field.name)
 			}
-			time.Sleep(300 * time.Millisecond)
+			browser.Sleep(ctx, 300*time.Millisecond)
 		}
 	}
-	
+
 	submitSelectors := []string{
 		"input[aria-labelledby='address-ui-widgets-form-submit-button-announce']",
 		"#address-ui-widgets-form-submit-button",
 		"[name='address-ui-widgets-form-submit-button']",
 	}
-	
+
 	for _, selector := range submitSelectors {
-		err := e.browser.Click(selector)
+		err := e.browser.Click(ctx, selector)
 		if err == nil {
-			time.Sleep(2 * time.Second)
+			browser.Sleep(ctx, 2*time.Second)
 			break
 		}
 	}
-	
+
 	return &ExecutionResult{
 		Success: true,
 		Message: "Address form filled",
 	}, nil
 }
 
-func (e *Executor) executeSelectPayment(step Step) (*ExecutionResult, error) {
+func (e *Executor) executeSelectPayment(ctx context.Context, step Step) (*ExecutionResult, error) {
 	paymentSelectors := []string{
 		"input[value='instrumentId=NetBanking']",
 		"input[value='SelectableAddCreditCard']",
 		"#pp-pNbbwp-127", // COD
 		"input[name='ppw-instrumentRowSelection']",
 	}
-	
-	fmt.Printf("\n💳 Select Payment Method\n")
-	fmt.Printf("Note: This is a simulation. Agent will select first available payment method.\n")
-	
+
+	e.logf("\n💳 Select Payment Method\n")
+	e.logf("Note: This is a simulation. Agent will select first available payment method.\n")
+
 	for _, selector := range paymentSelectors {
-		err := e.browser.WaitForSelector(selector, 2*time.Second)
+		err := e.browser.WaitForSelector(ctx, selector, 2*time.Second)
 		if err == nil {
-			err = e.browser.Click(selector)
+			err = e.browser.Click(ctx, selector)
 			if err == nil {
-				time.Sleep(1 * time.Second)
-				fmt.Printf("   ✓ Payment method selected\n")
+				browser.Sleep(ctx, 1*time.Second)
+				e.logf("   ✓ Payment method selected\n")
 				break
 			}
 		}
 	}
-	
+
 	continueSelectors := []string{
 		"input[name='ppw-widgetEvent:SetPaymentPlanSelectContinueEvent']",
 		"#continue-top",
 		"#bottomSubmitOrderButtonId",
 	}
-	
+
 	for _, selector := range continueSelectors {
-		err := e.browser.WaitForSelector(selector, 2*time.Second)
+		err := e.browser.WaitForSelector(ctx, selector, 2*time.Second)
 		if err == nil {
-			fmt.Printf("   ⚠️  Found 'Continue' button but NOT clicking (stopping before final order)\n")
+			e.logf("   ⚠️  Found 'Continue' button but NOT clicking (stopping before final order)\n")
 			break
 		}
 	}
-	
+
 	return &ExecutionResult{
 		Success: true,
 		Message: "Reached payment screen (stopped before final submission)",
 	}, nil
 }
 
-func (e *Executor) clickFirstItem(step Step) (*ExecutionResult, error) {
-	fmt.Println("   🔍 Looking for the first product link...")
+func (e *Executor) clickFirstItem(ctx context.Context, step Step) (*ExecutionResult, error) {
+	e.logf("   🔍 Looking for the first product link...\n")
 
 	script := `
 	() => {
@@ -592,14 +660,14 @@ func (e *Executor) clickFirstItem(step Step) (*ExecutionResult, error) {
 	}
 	`
 
-	result, err := e.browser.Evaluate(script)
+	result, err := e.browser.Evaluate(ctx, script)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute click script: %w", err)
 	}
 
 	if resultMap, ok := result.(map[string]interface{}); ok {
 		if success, ok := resultMap["success"].(bool); ok && success {
-			time.Sleep(3 * time.Second)
+			browser.Sleep(ctx, 3*time.Second)
 			return &ExecutionResult{
 				Success: true,
 				Message: "Clicked the link of the first product.",
@@ -610,18 +678,18 @@ func (e *Executor) clickFirstItem(step Step) (*ExecutionResult, error) {
 	return nil, fmt.Errorf("could not click first item")
 }
 
-func (e *Executor) executeDynamicSearch(step Step, ctx *ExecutionContext) (*ExecutionResult, error) {
-	searchTerm := e.extractSearchTerm(step, ctx)
-	fmt.Printf("   🔍 Search term extracted: '%s'\n", searchTerm)
+func (e *Executor) executeDynamicSearch(ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+	searchTerm := e.extractSearchTerm(ctx, step, execCtx)
+	e.logf("   🔍 Search term extracted: '%s'\n", searchTerm)
 
 	if step.Target != "" {
-		return e.executeTypingAction(step, searchTerm)
+		return e.executeTypingAction(ctx, step, searchTerm)
 	}
 
-	return e.findAndUseSearchBox(searchTerm)
+	return e.findAndUseSearchBox(ctx, searchTerm)
 }
 
-func (e *Executor) extractSearchTerm(step Step, ctx *ExecutionContext) string {
+func (e *Executor) extractSearchTerm(ctx context.Context, step Step, execCtx *ExecutionContext) string {
 	if step.Value != nil {
 		switch v := step.Value.(type) {
 		case string:
@@ -651,8 +719,8 @@ func (e *Executor) extractSearchTerm(step Step, ctx *ExecutionContext) string {
 		}
 	}
 
-	if ctx != nil && ctx.TaskDescription != "" {
-		taskLower := strings.ToLower(ctx.TaskDescription)
+	if execCtx != nil && execCtx.TaskDescription != "" {
+		taskLower := strings.ToLower(execCtx.TaskDescription)
 		searchPatterns := []string{"search for ", "search ", "find ", "look for "}
 
 		for _, pattern := range searchPatterns {
@@ -679,26 +747,26 @@ func (e *Executor) extractSearchTerm(step Step, ctx *ExecutionContext) string {
 	return "product"
 }
 
-func (e *Executor) executeTypingAction(step Step, searchTerm string) (*ExecutionResult, error) {
-	err := e.browser.Click(step.Target)
+func (e *Executor) executeTypingAction(ctx context.Context, step Step, searchTerm string) (*ExecutionResult, error) {
+	err := e.browser.Click(ctx, step.Target)
 	if err == nil {
-		time.Sleep(300 * time.Millisecond)
-		e.browser.Press(step.Target, "Control+a")
-		time.Sleep(100 * time.Millisecond)
-		e.browser.Press(step.Target, "Delete")
+		browser.Sleep(ctx, 300*time.Millisecond)
+		e.browser.Press(ctx, step.Target, "Control+a")
+		browser.Sleep(ctx, 100*time.Millisecond)
+		e.browser.Press(ctx, step.Target, "Delete")
 	}
 
-	err = e.browser.Type(step.Target, searchTerm)
+	err = e.browser.Type(ctx, step.Target, searchTerm)
 	if err != nil {
 		return nil, fmt.Errorf("type into %s: %w", step.Target, err)
 	}
 
 	if step.Parameters != nil && step.Parameters["submit"] == "true" {
-		time.Sleep(500 * time.Millisecond)
-		e.browser.Press(step.Target, "Enter")
+		browser.Sleep(ctx, 500*time.Millisecond)
+		e.browser.Press(ctx, step.Target, "Enter")
 	}
 
-	time.Sleep(2 * time.Second)
+	browser.Sleep(ctx, 2*time.Second)
 
 	return &ExecutionResult{
 		Success: true,
@@ -706,7 +774,7 @@ func (e *Executor) executeTypingAction(step Step, searchTerm string) (*Execution
 	}, nil
 }
 
-func (e *Executor) findAndUseSearchBox(searchTerm string) (*ExecutionResult, error) {
+func (e *Executor) findAndUseSearchBox(ctx context.Context, searchTerm string) (*ExecutionResult, error) {
 	searchBoxSelectors := []string{
 		"#twotabsearchtextbox",
 		"input[type='text']",
@@ -716,19 +784,19 @@ func (e *Executor) findAndUseSearchBox(searchTerm string) (*ExecutionResult, err
 	}
 
 	for _, selector := range searchBoxSelectors {
-		err := e.browser.WaitForSelector(selector, 1*time.Second)
+		err := e.browser.WaitForSelector(ctx, selector, 1*time.Second)
 		if err == nil {
-			e.browser.Click(selector)
-			time.Sleep(300 * time.Millisecond)
+			e.browser.Click(ctx, selector)
+			browser.Sleep(ctx, 300*time.Millisecond)
 
-			err = e.browser.Type(selector, searchTerm)
+			err = e.browser.Type(ctx, selector, searchTerm)
 			if err != nil {
 				continue
 			}
 
-			time.Sleep(500 * time.Millisecond)
-			e.browser.Press(selector, "Enter")
-			time.Sleep(2 * time.Second)
+			browser.Sleep(ctx, 500*time.Millisecond)
+			e.browser.Press(ctx, selector, "Enter")
+			browser.Sleep(ctx, 2*time.Second)
 
 			return &ExecutionResult{
 				Success: true,
@@ -740,7 +808,7 @@ func (e *Executor) findAndUseSearchBox(searchTerm string) (*ExecutionResult, err
 	return nil, fmt.Errorf("could not find a search box")
 }
 
-func (e *Executor) executeType(step Step) (*ExecutionResult, error) {
+func (e *Executor) executeType(ctx context.Context, step Step) (*ExecutionResult, error) {
 	if step.Target == "" {
 		return nil, fmt.Errorf("type requires target selector")
 	}
@@ -750,22 +818,22 @@ func (e *Executor) executeType(step Step) (*ExecutionResult, error) {
 		return nil, fmt.Errorf("type requires value")
 	}
 
-	err := e.browser.WaitForSelector(step.Target, 5*time.Second)
+	err := e.browser.WaitForSelector(ctx, step.Target, 5*time.Second)
 	if err != nil {
 		return nil, fmt.Errorf("wait for %s: %w", step.Target, err)
 	}
 
-	err = e.browser.Type(step.Target, value)
+	err = e.browser.Type(ctx, step.Target, value)
 	if err != nil {
 		return nil, fmt.Errorf("type into %s: %w", step.Target, err)
 	}
 
 	if step.Parameters != nil && step.Parameters["submit"] == "true" {
-		time.Sleep(500 * time.Millisecond)
-		e.browser.Press(step.Target, "Enter")
+		browser.Sleep(ctx, 500*time.Millisecond)
+		e.browser.Press(ctx, step.Target, "Enter")
 	}
 
-	time.Sleep(500 * time.Millisecond)
+	browser.Sleep(ctx, 500*time.Millisecond)
 
 	return &ExecutionResult{
 		Success: true,
@@ -773,7 +841,7 @@ func (e *Executor) executeType(step Step) (*ExecutionResult, error) {
 	}, nil
 }
 
-func (e *Executor) executeWait(step Step) (*ExecutionResult, error) {
+func (e *Executor) executeWait(ctx context.Context, step Step) (*ExecutionResult, error) {
 	duration := 2 * time.Second
 
 	value := step.GetValueString()
@@ -787,10 +855,10 @@ func (e *Executor) executeWait(step Step) (*ExecutionResult, error) {
 	if step.Target != "" {
 		// Try multiple common selectors for the target
 		selectors := []string{step.Target}
-		
+
 		// Add fallback selectors for common elements
 		if strings.Contains(step.Target, "productTitle") {
-			selectors = append(selectors, 
+			selectors = append(selectors,
 				"#productTitle",
 				"#title",
 				"h1.product-title",
@@ -798,10 +866,10 @@ func (e *Executor) executeWait(step Step) (*ExecutionResult, error) {
 				"h1[id='title']",
 			)
 		}
-		
+
 		var lastErr error
 		for _, selector := range selectors {
-			err := e.browser.WaitForSelector(selector, duration)
+			err := e.browser.WaitForSelector(ctx, selector, duration)
 			if err == nil {
 				return &ExecutionResult{
 					Success: true,
@@ -810,41 +878,41 @@ func (e *Executor) executeWait(step Step) (*ExecutionResult, error) {
 			}
 			lastErr = err
 		}
-		
+
 		// If all selectors failed, check if we're at least on the right page type
-		pageState, _ := e.browser.GetPageState()
+		pageState := e.currentPage(ctx)
 		if strings.Contains(step.Target, "productTitle") {
 			if strings.Contains(pageState.URL, "/dp/") || strings.Contains(pageState.URL, "/gp/product/") {
-				fmt.Printf("   ⚠️  Product title selector not found, but we're on a product page\n")
-				time.Sleep(2 * time.Second)
+				e.logf("   ⚠️  Product title selector not found, but we're on a product page\n")
+				browser.Sleep(ctx, 2*time.Second)
 				return &ExecutionResult{
 					Success: true,
 					Message: "On product page (selector not found but page loaded)",
 				}, nil
 			}
 		}
-		
+
 		return nil, fmt.Errorf("wait for %s: %w", step.Target, lastErr)
 	}
 
-	time.Sleep(duration)
+	browser.Sleep(ctx, duration)
 	return &ExecutionResult{
 		Success: true,
 		Message: fmt.Sprintf("Waited %v", duration),
 	}, nil
 }
 
-func (e *Executor) executeExtract(step Step) (*ExecutionResult, error) {
+func (e *Executor) executeExtract(ctx context.Context, step Step) (*ExecutionResult, error) {
 	if step.Target == "" {
 		return nil, fmt.Errorf("extract requires target selector")
 	}
 
-	text, err := e.browser.GetText(step.Target)
+	text, err := e.browser.GetText(ctx, step.Target)
 	if err != nil {
 		return nil, fmt.Errorf("extract from %s: %w", step.Target, err)
 	}
 
-	fmt.Printf("   📄 Extracted: %s\n", text)
+	e.logf("   📄 Extracted: %s\n", text)
 
 	return &ExecutionResult{
 		Success: true,
@@ -852,14 +920,14 @@ func (e *Executor) executeExtract(step Step) (*ExecutionResult, error) {
 	}, nil
 }
 
-func (e *Executor) executeVerify(step Step) (*ExecutionResult, error) {
-	pageState, err := e.browser.GetPageState()
+func (e *Executor) executeVerify(ctx context.Context, step Step) (*ExecutionResult, error) {
+	pageState, err := e.browser.GetPageState(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get page state: %w", err)
 	}
 
 	if step.Target != "" {
-		err := e.browser.WaitForSelector(step.Target, 3*time.Second)
+		err := e.browser.WaitForSelector(ctx, step.Target, 3*time.Second)
 		if err != nil {
 			return nil, fmt.Errorf("verification failed: element %s not found", step.Target)
 		}
@@ -880,9 +948,7 @@ func (e *Executor) executeVerify(step Step) (*ExecutionResult, error) {
 	}, nil
 }
 
-func (e *Executor) executeRequestAuth(step Step) (*ExecutionResult, error) {
-	reader := bufio.NewReader(os.Stdin)
-
+func (e *Executor) executeRequestAuth(ctx context.Context, step Step) (*ExecutionResult, error) {
 	authType := "full"
 	if step.Parameters != nil && step.Parameters["type"] != "" {
 		if authTypeVal, ok := step.Parameters["type"]; ok {
@@ -892,12 +958,21 @@ func (e *Executor) executeRequestAuth(step Step) (*ExecutionResult, error) {
 		}
 	}
 
-	fmt.Printf("\n🔐 Amazon Login Required\n")
-	fmt.Printf("========================================\n")
+	if e.signedIn(ctx) {
+		e.logf("   ✅ Already signed in, skipping login\n")
+		return &ExecutionResult{
+			Success: true,
+			Message: "Session already authenticated",
+			Data:    map[string]interface{}{"signed_in": true},
+		}, nil
+	}
+
+	e.logf("\n🔐 Amazon Login Required\n")
+	e.logf("========================================\n")
 
 	// Check which page we're on
-	pageState, _ := e.browser.GetPageState()
-	fmt.Printf("Current page: %s\n\n", pageState.Title)
+	pageState := e.currentPage(ctx)
+	e.logf("Current page: %s\n\n", pageState.Title)
 
 	// First, try to enter email/phone
 	if authType == "email" || authType == "full" {
@@ -909,32 +984,33 @@ func (e *Executor) executeRequestAuth(step Step) (*ExecutionResult, error) {
 			"#username",
 		}
 
-		fmt.Print("📧 Email/Phone: ")
-		emailInput, _ := reader.ReadString('\n')
-		email := strings.TrimSpace(emailInput)
+		email, err := e.prompter.Prompt(ctx, "email", "📧 Email/Phone: ")
+		if err != nil {
+			return nil, err
+		}
 
 		if email != "" {
 			emailEntered := false
 			for _, selector := range emailSelectors {
-				err := e.browser.WaitForSelector(selector, 2*time.Second)
+				err := e.browser.WaitForSelector(ctx, selector, 2*time.Second)
 				if err == nil {
 					// Clear field first
-					e.browser.Click(selector)
-					time.Sleep(200 * time.Millisecond)
-					
-					err = e.browser.Type(selector, email)
+					e.browser.Click(ctx, selector)
+					browser.Sleep(ctx, 200*time.Millisecond)
+
+					err = e.browser.Type(ctx, selector, email)
 					if err == nil {
-						fmt.Printf("   ✓ Email entered in field: %s\n", selector)
+						e.logf("   ✓ Email entered in field: %s\n", selector)
 						emailEntered = true
 						e.memory.UserCredentials["email"] = email
-						time.Sleep(500 * time.Millisecond)
+						browser.Sleep(ctx, 500*time.Millisecond)
 						break
 					}
 				}
 			}
 
 			if !emailEntered {
-				fmt.Printf("   ⚠️  Could not find email field, trying to continue...\n")
+				e.logf("   ⚠️  Could not find email field, trying to continue...\n")
 			}
 
 			// Try to click "Continue" button after email
@@ -947,12 +1023,12 @@ func (e *Executor) executeRequestAuth(step Step) (*ExecutionResult, error) {
 			}
 
 			for _, selector := range continueSelectors {
-				err := e.browser.WaitForSelector(selector, 1*time.Second)
+				err := e.browser.WaitForSelector(ctx, selector, 1*time.Second)
 				if err == nil {
-					err = e.browser.Click(selector)
+					err = e.browser.Click(ctx, selector)
 					if err == nil {
-						fmt.Printf("   ✓ Clicked continue button\n")
-						time.Sleep(3 * time.Second) // Wait for password page
+						e.logf("   ✓ Clicked continue button\n")
+						browser.Sleep(ctx, 3*time.Second) // Wait for password page
 						break
 					}
 				}
@@ -969,35 +1045,32 @@ func (e *Executor) executeRequestAuth(step Step) (*ExecutionResult, error) {
 			"#password",
 		}
 
-		fmt.Print("🔒 Password: ")
-		passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
+		password, err := e.prompter.PromptSecret(ctx, "password", "🔒 Password: ")
 		if err != nil {
-			return nil, fmt.Errorf("read password: %w", err)
+			return nil, err
 		}
-		password := string(passwordBytes)
-		fmt.Println() // New line after hidden input
 
 		if password != "" {
 			passwordEntered := false
 			for _, selector := range passwordSelectors {
-				err := e.browser.WaitForSelector(selector, 2*time.Second)
+				err := e.browser.WaitForSelector(ctx, selector, 2*time.Second)
 				if err == nil {
 					// Clear field first
-					e.browser.Click(selector)
-					time.Sleep(200 * time.Millisecond)
-					
-					err = e.browser.Type(selector, password)
+					e.browser.Click(ctx, selector)
+					browser.Sleep(ctx, 200*time.Millisecond)
+
+					err = e.browser.Type(ctx, selector, password)
 					if err == nil {
-						fmt.Printf("   ✓ Password entered\n")
+						e.logf("   ✓ Password entered\n")
 						passwordEntered = true
-						time.Sleep(500 * time.Millisecond)
+						browser.Sleep(ctx, 500*time.Millisecond)
 						break
 					}
 				}
 			}
 
 			if !passwordEntered {
-				fmt.Printf("   ⚠️  Could not find password field\n")
+				e.logf("   ⚠️  Could not find password field\n")
 				return nil, fmt.Errorf("password field not found")
 			}
 		}
@@ -1013,30 +1086,30 @@ func (e *Executor) executeRequestAuth(step Step) (*ExecutionResult, error) {
 		"button[type='submit']",
 	}
 
-	fmt.Printf("\n🔄 Submitting login form...\n")
+	e.logf("\n🔄 Submitting login form...\n")
 	submitted := false
 	for _, selector := range submitSelectors {
-		err := e.browser.WaitForSelector(selector, 2*time.Second)
+		err := e.browser.WaitForSelector(ctx, selector, 2*time.Second)
 		if err == nil {
-			err = e.browser.Click(selector)
+			err = e.browser.Click(ctx, selector)
 			if err == nil {
-				fmt.Printf("   ✓ Login form submitted\n")
+				e.logf("   ✓ Login form submitted\n")
 				submitted = true
-				time.Sleep(4 * time.Second) // Wait for login to process
+				browser.Sleep(ctx, 4*time.Second) // Wait for login to process
 				break
 			}
 		}
 	}
 
 	if !submitted {
-		fmt.Printf("   ⚠️  Could not find submit button, trying Enter key...\n")
+		e.logf("   ⚠️  Could not find submit button, trying Enter key...\n")
 		// Try pressing Enter as fallback
 		passwordSelectors := []string{"#ap_password", "input[type='password']"}
 		for _, selector := range passwordSelectors {
-			err := e.browser.Press(selector, "Enter")
+			err := e.browser.Press(ctx, selector, "Enter")
 			if err == nil {
-				fmt.Printf("   ✓ Submitted via Enter key\n")
-				time.Sleep(4 * time.Second)
+				e.logf("   ✓ Submitted via Enter key\n")
+				browser.Sleep(ctx, 4*time.Second)
 				submitted = true
 				break
 			}
@@ -1044,26 +1117,70 @@ func (e *Executor) executeRequestAuth(step Step) (*ExecutionResult, error) {
 	}
 
 	// Check if login was successful
-	time.Sleep(2 * time.Second)
-	pageState, _ = e.browser.GetPageState()
-	
-	fmt.Printf("========================================\n")
+	browser.Sleep(ctx, 2*time.Second)
+	pageState = e.currentPage(ctx)
+
+	e.logf("========================================\n")
+	signedIn := false
 	if strings.Contains(strings.ToLower(pageState.URL), "signin") || strings.Contains(strings.ToLower(pageState.URL), "ap/signin") {
-		fmt.Printf("⚠️  Still on signin page - login may have failed\n")
-		fmt.Printf("   Please check credentials or handle 2FA if prompted\n")
+		e.logf("⚠️  Still on signin page - login may have failed\n")
+		e.logf("   Please check credentials or handle 2FA if prompted\n")
 	} else {
-		fmt.Printf("✅ Login appears successful!\n")
+		e.logf("✅ Login appears successful!\n")
+		signedIn = true
 	}
-	fmt.Printf("========================================\n\n")
+	e.logf("========================================\n\n")
 
 	return &ExecutionResult{
 		Success: true,
 		Message: "Login credentials entered and submitted",
+		Data:    map[string]interface{}{"signed_in": signedIn},
 	}, nil
 }
 
-func (e *Executor) executeSmartAction(step Step, ctx *ExecutionContext) (*ExecutionResult, error) {
-	pageState, err := e.browser.GetPageState()
+// signedIn reports whether the browser session is already authenticated,
+// e.g. restored from a saved session: no sign-in form is showing and the
+// nav bar greets the account holder rather than offering to sign in.
+func (e *Executor) signedIn(ctx context.Context) bool {
+	url := strings.ToLower(e.currentPage(ctx).URL)
+	if url == "" || strings.Contains(url, "/ap/signin") {
+		return false
+	}
+
+	for _, selector := range []string{"#ap_email", "#ap_password"} {
+		if e.browser.WaitForSelector(ctx, selector, 500*time.Millisecond) == nil {
+			return false
+		}
+	}
+
+	greeting, err := e.browser.GetText(ctx, "#nav-link-accountList-nav-line-1")
+	if err != nil {
+		// Checkout pages have no nav bar; reaching one without being sent
+		// to sign in means the session is authenticated.
+		return strings.Contains(url, "/checkout") || strings.Contains(url, "/gp/buy")
+	}
+	greeting = strings.ToLower(strings.TrimSpace(greeting))
+	return strings.HasPrefix(greeting, "hello,") && !strings.Contains(greeting, "sign in")
+}
+
+// smartSuggestion is the LLM's answer to a smart_action prompt.
+type smartSuggestion struct {
+	Action     string      `json:"action"`
+	Selector   string      `json:"selector"`
+	Value      interface{} `json:"value"`
+	Submit     bool        `json:"submit"`
+	Confidence float64     `json:"confidence"`
+}
+
+// smartActions are the actions a smart_action suggestion may use.
+var smartActions = []string{"click", "type", "wait"}
+
+// executeSmartAction asks the LLM how to carry out the step on the current
+// page and runs its suggestion through the regular click, type or wait
+// action. Suggestions that can't be parsed, don't validate or fall below
+// the executor's confidence threshold fail the step.
+func (e *Executor) executeSmartAction(ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+	pageState, err := e.browser.GetPageState(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get page state: %w", err)
 	}
@@ -1082,25 +1199,92 @@ Determine the exact CSS selector to interact with and the action to take (click,
 Return JSON:
 {
   "action": "click|type|wait",
-  "selector": "exact CSS selector",
-  "value": "text if typing",
+  "selector": "exact CSS selector (optional for wait)",
+  "value": "text if typing, duration like 3s if waiting",
+  "submit": true/false (type only: press Enter after typing),
   "confidence": 0.0-1.0
-}`, pageState.URL, pageState.Title, ctx.TaskDescription, step.Description, pageState.Content[:min(1000, len(pageState.Content))])
+}`, pageState.URL, pageState.Title, execCtx.TaskDescription, step.Description, truncate(pageState.Content, 1000))
 
-	response, err := e.llm.Generate(prompt)
+	response, err := e.llm.Generate(ctx, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("get smart action: %w", err)
 	}
 
-	fmt.Printf("   🤖 AI suggested: %s\n", response)
+	suggestion, err := parseSmartSuggestion(response)
+	if err != nil {
+		return nil, fmt.Errorf("smart action: %w", err)
+	}
+	e.logf("   🤖 AI suggested: %s %s (confidence %.2f)\n", suggestion.Action, suggestion.Selector, suggestion.Confidence)
 
-	return &ExecutionResult{
-		Success: true,
-		Message: "Smart action completed",
-	}, nil
+	if suggestion.Confidence < e.smartActionConfidence {
+		return nil, fmt.Errorf("smart action: confidence %.2f of suggested %s is below the threshold of %.2f",
+			suggestion.Confidence, suggestion.Action, e.smartActionConfidence)
+	}
+
+	suggested := Step{
+		Action:      suggestion.Action,
+		Description: step.Description,
+		Target:      suggestion.Selector,
+		Value:       suggestion.Value,
+	}
+	if suggestion.Submit {
+		suggested.Parameters = map[string]interface{}{"submit": "true"}
+	}
+	action, _ := e.actions.Lookup(suggestion.Action)
+	result, err := action.Execute(ctx, e, suggested, execCtx)
+	if err != nil {
+		return nil, fmt.Errorf("smart action %s: %w", suggestion.Action, err)
+	}
+	if result != nil {
+		result.Message = "Smart action: " + result.Message
+	}
+	return result, nil
 }
 
-func (e *Executor) findAndClickAlternative(step Step, pageState *browser.PageState) (*ExecutionResult, error) {
+// parseSmartSuggestion decodes and validates a smart_action response.
+func parseSmartSuggestion(response string) (*smartSuggestion, error) {
+	response = stripCodeFence(response)
+	// Tolerate prose around the JSON object.
+	if start, end := strings.Index(response, "{"), strings.LastIndex(response, "}"); start >= 0 && end > start {
+		response = response[start : end+1]
+	}
+
+	var s smartSuggestion
+	if err := json.Unmarshal([]byte(response), &s); err != nil {
+		return nil, fmt.Errorf("unparsable suggestion: %w", err)
+	}
+
+	s.Action = strings.ToLower(strings.TrimSpace(s.Action))
+	s.Selector = strings.TrimSpace(s.Selector)
+	if !slices.Contains(smartActions, s.Action) {
+		return nil, fmt.Errorf("suggested action %q is not one of %s", s.Action, strings.Join(smartActions, ", "))
+	}
+	if s.Confidence < 0 || s.Confidence > 1 {
+		return nil, fmt.Errorf("confidence %v is outside 0-1", s.Confidence)
+	}
+
+	step := Step{Action: s.Action, Target: s.Selector, Value: s.Value}
+	value := step.GetValueString()
+	switch {
+	case s.Action != "wait" && s.Selector == "":
+		return nil, fmt.Errorf("suggested %s has no selector", s.Action)
+	case s.Selector != "":
+		if err := checkSelector(s.Selector); err != nil {
+			return nil, fmt.Errorf("suggested selector %q is invalid: %w", s.Selector, err)
+		}
+	}
+	if s.Action == "type" && value == "" {
+		return nil, fmt.Errorf("suggested type has no text")
+	}
+	if s.Action == "wait" && value != "" {
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("suggested wait %q is not a duration", value)
+		}
+	}
+	return &s, nil
+}
+
+func (e *Executor) findAndClickAlternative(ctx context.Context, step Step, pageState *browser.PageState) (*ExecutionResult, error) {
 	if strings.Contains(step.Description, "search") || strings.Contains(step.Target, "search") {
 		selectors := []string{
 			"#twotabsearchtextbox",
@@ -1111,7 +1295,7 @@ func (e *Executor) findAndClickAlternative(step Step, pageState *browser.PageSta
 		}
 
 		for _, selector := range selectors {
-			err := e.browser.Click(selector)
+			err := e.browser.Click(ctx, selector)
 			if err == nil {
 				return &ExecutionResult{
 					Success: true,
@@ -1129,15 +1313,15 @@ URL: %s
 
 Suggest an alternative CSS selector that might work. Return only the selector, nothing else.`, step.Target, step.Description, pageState.Title, pageState.URL)
 
-	response, err := e.llm.Generate(prompt)
+	response, err := e.llm.Generate(ctx, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("selector not found and AI fallback failed: %w", err)
 	}
 
 	altSelector := strings.TrimSpace(response)
-	fmt.Printf("   🔄 Trying alternative selector: %s\n", altSelector)
+	e.logf("   🔄 Trying alternative selector: %s\n", altSelector)
 
-	err = e.browser.Click(altSelector)
+	err = e.browser.Click(ctx, altSelector)
 	if err != nil {
 		return nil, fmt.Errorf("alternative selector also failed: %w", err)
 	}
@@ -1148,9 +1332,28 @@ Suggest an alternative CSS selector that might work. Return only the selector, n
 	}, nil
 }
 
+// currentPage returns the page state, or an empty one when it can't be read
+// (e.g. because ctx was cancelled), so callers can use it unconditionally.
+func (e *Executor) currentPage(ctx context.Context) *browser.PageState {
+	state, err := e.browser.GetPageState(ctx)
+	if err != nil || state == nil {
+		return &browser.PageState{}
+	}
+	return state
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
+
+// truncate cuts s to at most n runes, so multi-byte text such as a Hindi
+// product title isn't split mid-character.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,21 @@
+package amazon_agent
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"Logitech M331", 60, "Logitech M331"},
+		{"Logitech M331", 8, "Logitech"},
+		{"सर्फ एक्सेल डिटर्जेंट", 4, "सर्फ"},
+		{"₹₹₹", 2, "₹₹"},
+	}
+	for _, tc := range tests {
+		if got := truncate(tc.s, tc.n); got != tc.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tc.s, tc.n, got, tc.want)
+		}
+	}
+}
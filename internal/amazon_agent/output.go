@@ -0,0 +1,324 @@
+package amazon_agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// OutputSchema is the JSON Schema a task's structured output must match.
+// Only the subset below is understood; a schema using any other validation
+// keyword is rejected rather than silently not enforced.
+type OutputSchema struct {
+	Type       schemaTypes              `json:"type,omitempty"`
+	Properties map[string]*OutputSchema `json:"properties,omitempty"`
+	Required   []string                 `json:"required,omitempty"`
+	// AdditionalProperties is nil or true to allow properties not listed
+	// in Properties, false to reject them.
+	AdditionalProperties *bool         `json:"additionalProperties,omitempty"`
+	Items                *OutputSchema `json:"items,omitempty"`
+	Enum                 []interface{} `json:"enum,omitempty"`
+	MinItems             *int          `json:"minItems,omitempty"`
+	MaxItems             *int          `json:"maxItems,omitempty"`
+	Minimum              *float64      `json:"minimum,omitempty"`
+	Maximum              *float64      `json:"maximum,omitempty"`
+	MinLength            *int          `json:"minLength,omitempty"`
+}
+
+// schemaKeywords are the keywords ParseOutputSchema accepts: those
+// OutputSchema enforces and annotations that don't constrain values.
+var schemaKeywords = []string{
+	"type", "properties", "required", "additionalProperties", "items", "enum",
+	"minItems", "maxItems", "minimum", "maximum", "minLength",
+	"$schema", "$id", "title", "description", "format", "examples", "default",
+}
+
+// schemaTypes is the "type" keyword, either one type name or a list.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = many
+	return nil
+}
+
+// ParseOutputSchema decodes and checks a schema.
+func ParseOutputSchema(data []byte) (*OutputSchema, error) {
+	var s OutputSchema
+	if err := parseSchema(data, "$", &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func parseSchema(data []byte, path string, s *OutputSchema) error {
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return fmt.Errorf("schema %s: not a JSON object", path)
+	}
+	for keyword := range keywords {
+		if !slices.Contains(schemaKeywords, keyword) {
+			return fmt.Errorf("schema %s: unsupported keyword %q", path, keyword)
+		}
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return fmt.Errorf("schema %s: %w", path, err)
+	}
+	for _, t := range s.Type {
+		switch t {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return fmt.Errorf("schema %s: unknown type %q", path, t)
+		}
+	}
+	// Nested schemas were decoded without the keyword check; redo them.
+	if raw, ok := keywords["items"]; ok {
+		s.Items = &OutputSchema{}
+		if err := parseSchema(raw, path+"[]", s.Items); err != nil {
+			return err
+		}
+	}
+	if raw, ok := keywords["properties"]; ok {
+		var properties map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &properties); err != nil {
+			return fmt.Errorf("schema %s: properties must be an object", path)
+		}
+		s.Properties = make(map[string]*OutputSchema, len(properties))
+		for name, prop := range properties {
+			s.Properties[name] = &OutputSchema{}
+			if err := parseSchema(prop, path+"."+name, s.Properties[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadOutputSchema reads the output_schema setting: inline JSON, or the
+// path of a file holding it. An empty setting returns nil.
+func LoadOutputSchema(setting string) (json.RawMessage, error) {
+	setting = strings.TrimSpace(setting)
+	if setting == "" {
+		return nil, nil
+	}
+	data := []byte(setting)
+	if !strings.HasPrefix(setting, "{") {
+		var err error
+		if data, err = os.ReadFile(setting); err != nil {
+			return nil, fmt.Errorf("read output schema: %w", err)
+		}
+	}
+	if _, err := ParseOutputSchema(data); err != nil {
+		return nil, fmt.Errorf("output schema: %w", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, fmt.Errorf("output schema: %w", err)
+	}
+	return compact.Bytes(), nil
+}
+
+// Validate returns what is wrong with v, a value decoded by encoding/json,
+// one problem per line with the JSON path it was found at.
+func (s *OutputSchema) Validate(v interface{}) []string {
+	var problems []string
+	s.validate(v, "$", &problems)
+	return problems
+}
+
+func (s *OutputSchema) validate(v interface{}, path string, problems *[]string) {
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasType(v, t) }) {
+		*problems = append(*problems, fmt.Sprintf("%s: want %s, got %s", path, strings.Join(s.Type, " or "), typeName(v)))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e interface{}) bool { return jsonEqual(e, v) }) {
+		*problems = append(*problems, fmt.Sprintf("%s: %s is not one of the allowed values", path, jsonString(v)))
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				prop.validate(v[name], path+"."+name, problems)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*problems = append(*problems, fmt.Sprintf("%s: unexpected property %q", path, name))
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			*problems = append(*problems, fmt.Sprintf("%s: want at least %d items, got %d", path, *s.MinItems, len(v)))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			*problems = append(*problems, fmt.Sprintf("%s: want at most %d items, got %d", path, *s.MaxItems, len(v)))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			*problems = append(*problems, fmt.Sprintf("%s: %v is below the minimum %v", path, v, *s.Minimum))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			*problems = append(*problems, fmt.Sprintf("%s: %v is above the maximum %v", path, v, *s.Maximum))
+		}
+	case string:
+		if s.MinLength != nil && len([]rune(v)) < *s.MinLength {
+			*problems = append(*problems, fmt.Sprintf("%s: want at least %d characters, got %d", path, *s.MinLength, len([]rune(v))))
+		}
+	}
+}
+
+func hasType(v interface{}, t string) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case bool:
+		return t == "boolean"
+	case nil:
+		return t == "null"
+	}
+	return false
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func jsonString(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	return jsonString(a) == jsonString(b)
+}
+
+// outputData is the part of memory offered to the LLM to fill an output
+// schema: everything gathered during the run except credentials and
+// session data.
+func (m *AgentMemory) outputData() *AgentMemory {
+	data := *m
+	data.UserCredentials = nil
+	data.SessionData = nil
+	return &data
+}
+
+// ComposeOutput builds the task's output from the data gathered in memory
+// and validates it against schema. An output that can't be parsed or
+// doesn't match is sent back with the problems found, up to maxPlanRepairs
+// times.
+func (p *Planner) ComposeOutput(ctx context.Context, taskDescription string, schema json.RawMessage, memory *AgentMemory) (json.RawMessage, error) {
+	s, err := ParseOutputSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("output schema: %w", err)
+	}
+	data, err := json.MarshalIndent(memory.outputData(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode gathered data: %w", err)
+	}
+
+	prompt := fmt.Sprintf(`You are extracting the answer to a browser automation task from the data the agent gathered.
+
+Task: %s
+
+Data gathered during the run:
+%s
+
+Output JSON Schema:
+%s
+
+Build the answer from the gathered data only; never invent products, prices or links. Keep the order the task asks for (e.g. cheapest first).
+
+Return ONLY a JSON value matching the schema.`, taskDescription, data, schema)
+
+	request := prompt
+	for attempt := 0; ; attempt++ {
+		response, err := p.llm.Generate(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("generate output: %w", err)
+		}
+
+		output := []byte(stripCodeFence(response))
+		var value interface{}
+		var problems []string
+		if err := json.Unmarshal(output, &value); err != nil {
+			problems = []string{fmt.Sprintf("response is not valid JSON: %v", err)}
+		} else {
+			problems = s.Validate(value)
+		}
+		if len(problems) == 0 {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, output); err != nil {
+				return nil, fmt.Errorf("encode output: %w", err)
+			}
+			return compact.Bytes(), nil
+		}
+
+		if attempt == maxPlanRepairs {
+			return nil, fmt.Errorf("output rejected after %d attempts: does not match the schema: %s", attempt+1, strings.Join(problems, "; "))
+		}
+		p.logf("   ⚠️  Output has %d problem(s), asking the LLM to fix them\n", len(problems))
+		if short := truncate(response, 4000); short != response {
+			response = short + "..."
+		}
+		request = fmt.Sprintf(`%s
+
+Your previous response was rejected because of these problems:
+- %s
+
+Previous response:
+%s
+
+Fix every problem listed and return the complete corrected answer. Return ONLY valid JSON.`,
+			prompt, strings.Join(problems, "\n- "), response)
+	}
+}
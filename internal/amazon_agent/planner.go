@@ -1,29 +1,44 @@
 package amazon_agent
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"browser-agent/internal/browser"
 	"browser-agent/internal/llm"
 )
 
 type Planner struct {
-	llm *llm.GeminiClient
+	llm       llm.Client
+	actions   *ActionRegistry
+	validator *PlanValidator
+	// emit publishes progress messages; nil prints them to stdout.
+	emit func(Event)
 }
 
+// maxPlanRepairs is how many times a plan that fails validation is sent
+// back to the LLM with the problems found before the planner gives up.
+const maxPlanRepairs = 2
+
 type Plan struct {
 	Steps []Step `json:"steps"`
+	// OutputSchema is the JSON Schema of the answer the task asks for, if
+	// any: set by the planner for tasks that want data rather than only a
+	// purchase, or by the output_schema setting.
+	OutputSchema json.RawMessage `json:"output_schema,omitempty"`
 }
 
 type Step struct {
-	Action      string            `json:"action"`
-	Description string            `json:"description"`
-	Target      string            `json:"target,omitempty"`
-	Value       interface{}       `json:"value,omitempty"`
+	Action      string                 `json:"action"`
+	Description string                 `json:"description"`
+	Target      string                 `json:"target,omitempty"`
+	Value       interface{}            `json:"value,omitempty"`
 	Parameters  map[string]interface{} `json:"parameters,omitempty"`
-	Critical    bool              `json:"critical"`
+	Critical    bool                   `json:"critical"`
 }
 
 func (s *Step) GetValueString() string {
@@ -63,14 +78,113 @@ type ExecutedStep struct {
 	Step      Step
 	Success   bool
 	Error     error
-	Timestamp interface{}
+	Timestamp time.Time
+	// Artifacts are the page snapshots taken around the step, including
+	// those of its retries.
+	Artifacts []Artifact
+	// Retries is how many times the step was retried after its first
+	// attempt failed; Success and Error describe the last attempt.
+	Retries int
+}
+
+// NewPlanner returns a planner that offers the LLM the given actions and
+// validates its plans against them.
+func NewPlanner(llmClient llm.Client, actions *ActionRegistry) *Planner {
+	return &Planner{llm: llmClient, actions: actions, validator: NewPlanValidator(actions)}
+}
+
+func (p *Planner) logf(format string, args ...interface{}) {
+	if p.emit == nil {
+		fmt.Printf(format, args...)
+		return
+	}
+	p.emit(Event{Type: EventMessage, Message: fmt.Sprintf(format, args...)})
+}
+
+// generatePlan asks the LLM for a plan with prompt and validates it. A plan
+// that can't be parsed or fails validation is sent back with the problems
+// found, up to maxPlanRepairs times. kind names the plan in errors.
+func (p *Planner) generatePlan(ctx context.Context, kind, prompt string) (*Plan, error) {
+	request := prompt
+	for attempt := 0; ; attempt++ {
+		response, err := p.llm.Generate(ctx, request)
+		if err != nil {
+			return nil, fmt.Errorf("generate %s: %w", kind, err)
+		}
+
+		plan, err := parsePlan(response)
+		if err != nil {
+			err = &PlanError{Problems: []string{fmt.Sprintf("response is not a valid JSON plan: %v", err)}}
+		} else {
+			err = p.validator.Validate(plan)
+		}
+		if err == nil {
+			return plan, nil
+		}
+
+		var planErr *PlanError
+		if !errors.As(err, &planErr) || attempt == maxPlanRepairs {
+			return nil, fmt.Errorf("%s rejected after %d attempts: %w", kind, attempt+1, err)
+		}
+		p.logf("   ⚠️  %s has %d problem(s), asking the LLM to fix them\n", upperFirst(kind), len(planErr.Problems))
+		request = repairPrompt(prompt, response, planErr)
+	}
+}
+
+// repairPrompt repeats prompt with the rejected response and what was
+// wrong with it.
+func repairPrompt(prompt, response string, planErr *PlanError) string {
+	if short := truncate(response, 4000); short != response {
+		response = short + "..."
+	}
+	return fmt.Sprintf(`%s
+
+Your previous response was rejected because of these problems:
+- %s
+
+Previous response:
+%s
+
+Fix every problem listed and return the complete corrected plan. Return ONLY valid JSON in the same format.`,
+		prompt, strings.Join(planErr.Problems, "\n- "), response)
+}
+
+// parsePlan decodes an LLM response, with or without a Markdown code
+// fence, into a plan.
+func parsePlan(response string) (*Plan, error) {
+	var plan Plan
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
 }
 
-func NewPlanner(llmClient *llm.GeminiClient) *Planner {
-	return &Planner{llm: llmClient}
+// stripCodeFence removes a Markdown code fence around an LLM response.
+func stripCodeFence(response string) string {
+	response = strings.TrimSpace(response)
+	if strings.HasPrefix(response, "```json") {
+		response = strings.TrimPrefix(response, "```json")
+		response = strings.TrimSuffix(response, "```")
+		response = strings.TrimSpace(response)
+	} else if strings.HasPrefix(response, "```") {
+		response = strings.TrimPrefix(response, "```")
+		response = strings.TrimSuffix(response, "```")
+		response = strings.TrimSpace(response)
+	}
+	return response
 }
 
-func (p *Planner) CreatePlan(taskDescription string) (*Plan, error) {
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// CreatePlan plans taskDescription. With an outputSchema the plan must
+// gather the data it asks for; without one the planner may infer a schema
+// for tasks that ask for information.
+func (p *Planner) CreatePlan(ctx context.Context, taskDescription string, outputSchema json.RawMessage) (*Plan, error) {
 	prompt := fmt.Sprintf(`You are an advanced browser automation planner for complex e-commerce tasks. Create a comprehensive step-by-step plan for this task:
 
 Task: %s
@@ -90,20 +204,7 @@ You must generate a detailed plan with 30-50+ steps that covers:
 12. Final verification before order placement
 
 Available actions:
-- navigate: Go to URL (target: URL)
-- click: Click element (target: CSS selector)
-- type: Type text (target: selector, value: text, parameters: {submit: "true/false"})
-- wait: Wait for element or duration (target: selector optional, value: duration)
-- scroll: Scroll page (parameters: {direction: "up/down/top/bottom", amount: "500"})
-- go_back: Navigate back to previous page
-- select_product: Intelligently select product (value: criteria like "first", "rating above 4", "cheapest", "highest rated")
-- add_to_cart: Add current product to cart
-- proceed_checkout: Navigate to checkout from cart
-- login: Handle authentication - prompts user for email/password and fills them (parameters: {type: "full"})
-- fill_address: Fill shipping address form (prompts user)
-- select_payment: Select payment method
-- extract: Extract text (target: selector)
-- verify: Verify page state (target: selector optional, value: expected text)
+%s
 
 CRITICAL AUTHENTICATION GUIDELINES:
 1. After "proceed_checkout", expect a signin/login page
@@ -113,6 +214,7 @@ CRITICAL AUTHENTICATION GUIDELINES:
    - Fill the credentials into the form
    - Submit the login form
 4. After login action, add a wait step for page to load
+5. Always include the login step; it is skipped automatically when a saved session is already signed in
 
 CRITICAL SELECTOR GUIDELINES:
 1. Use SPECIFIC Amazon selectors like #twotabsearchtextbox, #add-to-cart-button
@@ -124,7 +226,13 @@ CRITICAL SELECTOR GUIDELINES:
 Important guidelines:
 1. Include wait steps after navigation (2-3 seconds)
 2. Add scrolling steps to explore products
-3. Use select_product for intelligent product selection
+3. Use select_product for intelligent product selection, with the task's criteria (price limit, minimum rating, cheapest, highest rated, non-sponsored) as its value
+   - Before it, let the site narrow the results: apply_filter for price, rating, Prime, brand or delivery constraints and sort_results for an order (e.g. "cheapest 4-star Prime mouse": apply_filter "4 stars & up, Prime", sort_results "price low to high")
+   - After it, when the task names a colour, size or other variation, use select_variant with those options as its value (e.g. "colour: black, size: L")
+   - For several products, repeat search, select_product and add_to_cart for each; give add_to_cart a "quantity" parameter when the task wants more than one (e.g. "2 USB cables and a charger": quantity "2" for the cable, none for the charger)
+   - To change the cart afterwards, use set_cart_quantity and remove_from_cart, naming the item by title words
+   - For tasks that ask about products rather than buying them, use extract_product on each product page to record its details
+   - Use read_cart when the task asks what is in the cart; proceed_checkout checks the cart against the items added by itself, so no verify step is needed before it
 4. Include verification steps after critical actions
 5. For login, use the "login" action with parameters: {"type": "full"}
 6. Break down address filling into logical steps
@@ -172,39 +280,27 @@ Return ONLY valid JSON in this format:
       "critical": false
     }
   ]
-}`, taskDescription)
+}`, taskDescription, p.actions.PromptList())
 
-	response, err := p.llm.Generate(prompt)
-	if err != nil {
-		return nil, fmt.Errorf("generate plan: %w", err)
-	}
-
-	response = strings.TrimSpace(response)
-	if strings.HasPrefix(response, "```json") {
-		response = strings.TrimPrefix(response, "```json")
-		response = strings.TrimSuffix(response, "```")
-		response = strings.TrimSpace(response)
-	} else if strings.HasPrefix(response, "```") {
-		response = strings.TrimPrefix(response, "```")
-		response = strings.TrimSuffix(response, "```")
-		response = strings.TrimSpace(response)
-	}
+	if len(outputSchema) > 0 {
+		prompt += fmt.Sprintf(`
 
-	var plan Plan
-	if err := json.Unmarshal([]byte(response), &plan); err != nil {
-		return nil, fmt.Errorf("parse plan: %w (response: %s)", err, response)
-	}
+The task's answer must match this JSON Schema, filled from the data the plan gathers:
+%s
+Include the steps that gather every field it needs (collect_products, extract_product, read_cart). Do not add an "output_schema" field.`, outputSchema)
+	} else {
+		prompt += `
 
-	if len(plan.Steps) == 0 {
-		return nil, fmt.Errorf("plan has no steps")
+If the task asks for information (a list of products, prices, links, an answer) rather than only a purchase, add an "output_schema" field next to "steps": a JSON Schema for the answer, using only type, properties, required, items, enum, minItems, maxItems, minimum, maximum and minLength. For example, for "the 5 cheapest 4-star headphones with price and link":
+"output_schema": {"type": "object", "required": ["products"], "properties": {"products": {"type": "array", "maxItems": 5, "items": {"type": "object", "required": ["title", "price", "url"], "properties": {"title": {"type": "string"}, "price": {"type": "number"}, "url": {"type": "string"}}}}}}`
 	}
 
-	return &plan, nil
+	return p.generatePlan(ctx, "plan", prompt)
 }
 
-func (p *Planner) Replan(ctx *ExecutionContext, reason string) (*Plan, error) {
+func (p *Planner) Replan(ctx context.Context, execCtx *ExecutionContext, reason string) (*Plan, error) {
 	executedStepsDesc := ""
-	for i, step := range ctx.ExecutedSteps {
+	for i, step := range execCtx.ExecutedSteps {
 		status := "✓"
 		if !step.Success {
 			status = "✗"
@@ -213,14 +309,15 @@ func (p *Planner) Replan(ctx *ExecutionContext, reason string) (*Plan, error) {
 	}
 
 	memoryInfo := ""
-	if ctx.Memory != nil {
+	if execCtx.Memory != nil {
 		memoryInfo = fmt.Sprintf(`
 Memory state:
 - Products found: %d
 - Selected product: %s
 - Cart items: %d
+- Product details extracted: %d
 - Current page: %s
-`, len(ctx.Memory.ProductURLs), ctx.Memory.SelectedProduct, len(ctx.Memory.CartItems), ctx.Memory.CurrentPage)
+`, len(execCtx.Memory.ProductURLs), execCtx.Memory.SelectedProduct, execCtx.Memory.CartCount(), len(execCtx.Memory.ProductDetails), execCtx.Memory.CurrentPage)
 	}
 
 	prompt := fmt.Sprintf(`You are a browser automation planner. The current plan needs adjustment.
@@ -239,58 +336,16 @@ Generate a NEW complete plan that:
 2. Addresses the issue that caused replanning
 3. Continues from current state to complete the task
 4. Maintains the same level of detail (20-40 steps)
-5. Every step MUST have a valid action from: navigate, click, type, wait, verify, login, scroll, select_product, add_to_cart, proceed_checkout, fill_address, select_payment, extract
-
-Return ONLY valid JSON in the same format as before.`, ctx.TaskDescription, executedStepsDesc, memoryInfo, reason)
-
-	response, err := p.llm.Generate(prompt)
-	if err != nil {
-		return nil, fmt.Errorf("generate replan: %w", err)
-	}
-
-	response = strings.TrimSpace(response)
-	if strings.HasPrefix(response, "```json") {
-		response = strings.TrimPrefix(response, "```json")
-		response = strings.TrimSuffix(response, "```")
-		response = strings.TrimSpace(response)
-	} else if strings.HasPrefix(response, "```") {
-		response = strings.TrimPrefix(response, "```")
-		response = strings.TrimSuffix(response, "```")
-		response = strings.TrimSpace(response)
-	}
-
-	var plan Plan
-	if err := json.Unmarshal([]byte(response), &plan); err != nil {
-		return nil, fmt.Errorf("parse replan: %w", err)
-	}
-
-	if len(plan.Steps) == 0 {
-		return nil, fmt.Errorf("replan produced no steps")
-	}
+5. Every step MUST have a valid action from: %s
 
-	for i, step := range plan.Steps {
-		if step.Action == "" {
-			return nil, fmt.Errorf("step %d has empty action", i+1)
-		}
+Return ONLY valid JSON in the same format as before.`, execCtx.TaskDescription, executedStepsDesc, memoryInfo, reason, strings.Join(p.actions.Names(), ", "))
 
-		switch step.Action {
-		case "click", "type", "extract", "verify":
-			if step.Target == "" {
-				return nil, fmt.Errorf("step %d (action: %s) must have a target selector", i+1, step.Action)
-			}
-		case "navigate":
-			if step.Target == "" {
-				return nil, fmt.Errorf("step %d (navigate) must have a URL", i+1)
-			}
-		}
-	}
-
-	return &plan, nil
+	return p.generatePlan(ctx, "replan", prompt)
 }
 
-func (p *Planner) CreateRecoveryPlan(ctx *ExecutionContext, pageState *browser.PageState, errorMsg string) (*Plan, error) {
+func (p *Planner) CreateRecoveryPlan(ctx context.Context, execCtx *ExecutionContext, pageState *browser.PageState, errorMsg string) (*Plan, error) {
 	executedStepsDesc := ""
-	for i, step := range ctx.ExecutedSteps[max(0, len(ctx.ExecutedSteps)-5):] {
+	for i, step := range execCtx.ExecutedSteps[max(0, len(execCtx.ExecutedSteps)-5):] {
 		status := "✓"
 		if !step.Success {
 			status = "✗"
@@ -299,8 +354,8 @@ func (p *Planner) CreateRecoveryPlan(ctx *ExecutionContext, pageState *browser.P
 	}
 
 	contentPreview := pageState.Content
-	if len(contentPreview) > 1000 {
-		contentPreview = contentPreview[:1000] + "..."
+	if short := truncate(contentPreview, 1000); short != contentPreview {
+		contentPreview = short + "..."
 	}
 
 	prompt := fmt.Sprintf(`You are a browser automation recovery planner. The agent encountered multiple consecutive failures.
@@ -323,30 +378,12 @@ Create a recovery plan that:
 3. Resumes the original task from a stable state
 4. Uses 10-20 steps to recover and continue
 
-Return ONLY valid JSON with recovery steps.`, ctx.TaskDescription, executedStepsDesc, errorMsg, pageState.URL, pageState.Title, contentPreview)
-
-	response, err := p.llm.Generate(prompt)
-	if err != nil {
-		return nil, fmt.Errorf("generate recovery plan: %w", err)
-	}
-
-	response = strings.TrimSpace(response)
-	if strings.HasPrefix(response, "```json") {
-		response = strings.TrimPrefix(response, "```json")
-		response = strings.TrimSuffix(response, "```")
-		response = strings.TrimSpace(response)
-	} else if strings.HasPrefix(response, "```") {
-		response = strings.TrimPrefix(response, "```")
-		response = strings.TrimSuffix(response, "```")
-		response = strings.TrimSpace(response)
-	}
+Available actions:
+%s
 
-	var plan Plan
-	if err := json.Unmarshal([]byte(response), &plan); err != nil {
-		return nil, fmt.Errorf("parse recovery plan: %w", err)
-	}
+Return ONLY valid JSON with recovery steps.`, execCtx.TaskDescription, executedStepsDesc, errorMsg, pageState.URL, pageState.Title, contentPreview, p.actions.PromptList())
 
-	return &plan, nil
+	return p.generatePlan(ctx, "recovery plan", prompt)
 }
 
 func max(a, b int) int {
@@ -354,4 +391,4 @@ func max(a, b int) int {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
@@ -0,0 +1,161 @@
+package amazon_agent
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"browser-agent/internal/browser"
+	"browser-agent/internal/llm"
+)
+
+// scriptedLLM replays testdata/<name>.json.
+func scriptedLLM(t *testing.T, name string) *llm.ScriptedClient {
+	t.Helper()
+	client, err := llm.NewScriptedClient(filepath.Join("testdata", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// testPlanner returns a planner with the built-in actions whose LLM
+// replays testdata/<name>.json.
+func testPlanner(t *testing.T, name string) (*Planner, *llm.ScriptedClient) {
+	t.Helper()
+	client := scriptedLLM(t, name)
+	planner := NewPlanner(client, NewExecutor(nil, client, &AgentMemory{}).Actions())
+	planner.emit = func(Event) {}
+	return planner, client
+}
+
+func actionNames(plan *Plan) []string {
+	var names []string
+	for _, step := range plan.Steps {
+		names = append(names, step.Action)
+	}
+	return names
+}
+
+func TestCreatePlan(t *testing.T) {
+	planner, client := testPlanner(t, "plan")
+
+	plan, err := planner.CreatePlan(context.Background(), "Buy the cheapest detergent", nil)
+	if err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	if names := actionNames(plan); !slices.Equal(names, []string{"navigate", "wait", "type", "select_product", "add_to_cart"}) {
+		t.Errorf("plan actions = %v", names)
+	}
+	if plan.OutputSchema != nil {
+		t.Errorf("OutputSchema = %s, want none", plan.OutputSchema)
+	}
+	if client.Calls() != 1 {
+		t.Errorf("LLM calls = %d, want 1", client.Calls())
+	}
+}
+
+func TestCreatePlanRepairsInvalidPlan(t *testing.T) {
+	planner, client := testPlanner(t, "plan_repaired")
+
+	plan, err := planner.CreatePlan(context.Background(), "Buy the cheapest detergent", nil)
+	if err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	if len(plan.Steps) != 5 {
+		t.Errorf("got %d steps, want the 5 of the repaired plan", len(plan.Steps))
+	}
+	if client.Calls() != 2 {
+		t.Errorf("LLM calls = %d, want 2", client.Calls())
+	}
+}
+
+func TestCreatePlanRejectedAfterRepairs(t *testing.T) {
+	planner, client := testPlanner(t, "plan_rejected")
+
+	_, err := planner.CreatePlan(context.Background(), "Buy the cheapest detergent", nil)
+	var planErr *PlanError
+	if !errors.As(err, &planErr) {
+		t.Fatalf("got %v, want a *PlanError", err)
+	}
+	// One problem for the unknown action, one for the click's target.
+	if len(planErr.Problems) != 2 {
+		t.Errorf("problems = %q, want 2", planErr.Problems)
+	}
+	if client.Calls() != maxPlanRepairs+1 {
+		t.Errorf("LLM calls = %d, want %d", client.Calls(), maxPlanRepairs+1)
+	}
+}
+
+func TestCreatePlanInfersOutputSchema(t *testing.T) {
+	planner, _ := testPlanner(t, "plan_output_schema")
+
+	plan, err := planner.CreatePlan(context.Background(), "List the cheapest detergents with their prices", nil)
+	if err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	schema, err := ParseOutputSchema(plan.OutputSchema)
+	if err != nil {
+		t.Fatalf("plan's output schema: %v", err)
+	}
+	if schema.Properties["products"] == nil {
+		t.Errorf("output schema = %s, want a products property", plan.OutputSchema)
+	}
+}
+
+func TestCreatePlanRepairsOutputSchema(t *testing.T) {
+	planner, client := testPlanner(t, "plan_bad_output_schema")
+
+	plan, err := planner.CreatePlan(context.Background(), "List the cheapest detergents with their prices", nil)
+	if err != nil {
+		t.Fatalf("CreatePlan: %v", err)
+	}
+	if _, err := ParseOutputSchema(plan.OutputSchema); err != nil {
+		t.Errorf("output schema of the repaired plan: %v", err)
+	}
+	if client.Calls() != 2 {
+		t.Errorf("LLM calls = %d, want 2", client.Calls())
+	}
+}
+
+func TestReplan(t *testing.T) {
+	planner, _ := testPlanner(t, "replan")
+	execCtx := &ExecutionContext{
+		TaskDescription: "Buy the cheapest detergent",
+		ExecutedSteps: []ExecutedStep{
+			{Step: Step{Action: "navigate", Description: "Open Amazon"}, Success: true},
+			{Step: Step{Action: "type", Description: "Search for detergent"}, Success: false},
+		},
+		Memory: &AgentMemory{},
+	}
+
+	plan, err := planner.Replan(context.Background(), execCtx, "the search box was not found")
+	if err != nil {
+		t.Fatalf("Replan: %v", err)
+	}
+	if names := actionNames(plan); !slices.Equal(names, []string{"type", "select_product", "add_to_cart"}) {
+		t.Errorf("replan actions = %v", names)
+	}
+}
+
+func TestCreateRecoveryPlan(t *testing.T) {
+	planner, _ := testPlanner(t, "recovery_plan")
+	execCtx := &ExecutionContext{
+		TaskDescription: "Buy the cheapest detergent",
+		ExecutedSteps: []ExecutedStep{
+			{Step: Step{Action: "select_product", Description: "Pick the cheapest"}, Success: false},
+		},
+		Memory: &AgentMemory{},
+	}
+	page := &browser.PageState{URL: "https://www.amazon.in/s?k=detergent", Title: "Amazon.in : detergent"}
+
+	plan, err := planner.CreateRecoveryPlan(context.Background(), execCtx, page, "no products matched")
+	if err != nil {
+		t.Fatalf("CreateRecoveryPlan: %v", err)
+	}
+	if names := actionNames(plan); !slices.Equal(names, []string{"go_back", "select_product", "add_to_cart"}) {
+		t.Errorf("recovery plan actions = %v", names)
+	}
+}
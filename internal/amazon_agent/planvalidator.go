@@ -0,0 +1,265 @@
+package amazon_agent
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PlanValidator checks plans from the LLM against the executor's action
+// registry before any of their steps run.
+type PlanValidator struct {
+	actions *ActionRegistry
+}
+
+func NewPlanValidator(actions *ActionRegistry) *PlanValidator {
+	return &PlanValidator{actions: actions}
+}
+
+// PlanError lists everything wrong with a plan.
+type PlanError struct {
+	Problems []string
+}
+
+func (e *PlanError) Error() string {
+	if len(e.Problems) == 1 {
+		return "invalid plan: " + e.Problems[0]
+	}
+	return fmt.Sprintf("invalid plan: %d problems: %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// Validate returns a *PlanError if plan is empty or any of its steps uses
+// an unknown action, lacks a field its action requires, or has a malformed
+// URL, CSS selector or duration, or if its output schema is malformed.
+func (v *PlanValidator) Validate(plan *Plan) error {
+	if plan == nil || len(plan.Steps) == 0 {
+		return &PlanError{Problems: []string{"plan has no steps"}}
+	}
+
+	var problems []string
+	for i, step := range plan.Steps {
+		for _, problem := range v.validateStep(step) {
+			problems = append(problems, fmt.Sprintf("step %d (%s): %s", i+1, step.Action, problem))
+		}
+	}
+	if len(plan.OutputSchema) > 0 {
+		if _, err := ParseOutputSchema(plan.OutputSchema); err != nil {
+			problems = append(problems, fmt.Sprintf("output_schema: %v", err))
+		}
+	}
+	if len(problems) > 0 {
+		return &PlanError{Problems: problems}
+	}
+	return nil
+}
+
+func (v *PlanValidator) validateStep(step Step) []string {
+	if step.Action == "" {
+		return []string{"action is empty"}
+	}
+	action, ok := v.actions.Lookup(step.Action)
+	if !ok {
+		return []string{fmt.Sprintf("unknown action (known actions: %s)", strings.Join(v.actions.Names(), ", "))}
+	}
+	spec := action.Schema()
+
+	var problems []string
+	value := step.GetValueString()
+	switch {
+	case spec.TargetRequired && step.Target == "":
+		problems = append(problems, fmt.Sprintf("target is required (a %s)", targetDescription(spec.Target)))
+	case step.Target != "" && spec.Target == TargetURL:
+		if err := checkURL(step.Target); err != nil {
+			problems = append(problems, fmt.Sprintf("target %q: %v", step.Target, err))
+		}
+	case step.Target != "" && spec.Target == TargetSelector:
+		if err := checkSelector(step.Target); err != nil {
+			problems = append(problems, fmt.Sprintf("target %q is not a valid selector: %v", step.Target, err))
+		}
+	}
+	if spec.ValueRequired && value == "" {
+		problems = append(problems, "value is required")
+	}
+	if spec.TargetOrValue && step.Target == "" && value == "" {
+		problems = append(problems, "needs a target selector or an expected value")
+	}
+	if spec.ValueDuration && value != "" {
+		if _, err := time.ParseDuration(value); err != nil {
+			problems = append(problems, fmt.Sprintf("value %q is not a duration (e.g. 3s)", value))
+		}
+	}
+	for _, param := range spec.Params {
+		value, ok := step.Parameters[param.Name]
+		if !ok || len(param.Values) == 0 {
+			continue
+		}
+		if s := fmt.Sprint(value); !slices.Contains(param.Values, s) {
+			problems = append(problems, fmt.Sprintf("parameter %s is %q, want one of %s", param.Name, s, strings.Join(param.Values, ", ")))
+		}
+	}
+	return problems
+}
+
+func targetDescription(kind string) string {
+	switch kind {
+	case TargetURL:
+		return "URL"
+	case TargetSelector:
+		return "CSS selector"
+	}
+	return "value"
+}
+
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("not an absolute http(s) URL")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("URL has no host")
+	}
+	return nil
+}
+
+// selectorEngines are the Playwright selector engines a step may name
+// explicitly, as in "text=Add to Cart".
+var selectorEngines = []string{"css", "text", "xpath", "id", "data-testid", "data-test-id", "data-test", "role", "nth", "internal:"}
+
+// checkSelector catches selectors that Playwright would reject outright:
+// unbalanced brackets or quotes, dangling combinators and malformed ID,
+// class or attribute parts. Parts of a ">>" chain that use another
+// selector engine are only checked for being non-empty.
+func checkSelector(selector string) error {
+	for _, part := range strings.Split(selector, ">>") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return fmt.Errorf("empty selector")
+		}
+		if strings.HasPrefix(part, "//") || strings.HasPrefix(part, "..") {
+			continue // XPath
+		}
+		if engine, rest, ok := strings.Cut(part, "="); ok && isEngine(engine) {
+			if strings.TrimSpace(engine) == "css" {
+				if err := checkCSS(strings.TrimSpace(rest)); err != nil {
+					return err
+				}
+			} else if strings.TrimSpace(rest) == "" {
+				return fmt.Errorf("empty %s selector", engine)
+			}
+			continue
+		}
+		if err := checkCSS(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isEngine(name string) bool {
+	name = strings.TrimSpace(name)
+	for _, engine := range selectorEngines {
+		if name == engine || (strings.HasSuffix(engine, ":") && strings.HasPrefix(name, engine)) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkCSS(css string) error {
+	if css == "" {
+		return fmt.Errorf("empty selector")
+	}
+
+	var stack []rune
+	var quote rune
+	// expectName is set after '#', '.' or '[' until the name they
+	// introduce starts.
+	expectName := rune(0)
+	// lastSignificant is the last non-space rune outside quotes and
+	// brackets, to spot dangling combinators.
+	lastSignificant := rune(0)
+
+	runes := []rune(css)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if quote != 0 {
+			switch r {
+			case '\\':
+				i++
+			case quote:
+				quote = 0
+			}
+			continue
+		}
+
+		if expectName != 0 {
+			if !isNameStart(r, expectName) {
+				return fmt.Errorf("%q at offset %d must be followed by a name", expectName, i-1)
+			}
+			expectName = 0
+		}
+
+		switch r {
+		case '\\':
+			i++
+		case '"', '\'':
+			quote = r
+		case '(', '[':
+			if r == '[' {
+				expectName = '['
+			}
+			stack = append(stack, r)
+		case ')', ']':
+			open := '('
+			if r == ']' {
+				open = '['
+			}
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				return fmt.Errorf("unbalanced %q at offset %d", r, i)
+			}
+			stack = stack[:len(stack)-1]
+		case '#', '.':
+			if len(stack) == 0 {
+				expectName = r
+			}
+		case '{', '}', ';':
+			if len(stack) == 0 {
+				return fmt.Errorf("unexpected %q at offset %d", r, i)
+			}
+		case '>', '+', '~', ',':
+			if len(stack) == 0 && (lastSignificant == 0 || strings.ContainsRune(">+~,", lastSignificant)) {
+				return fmt.Errorf("unexpected %q at offset %d", r, i)
+			}
+		}
+		if len(stack) == 0 && !unicode.IsSpace(r) {
+			lastSignificant = r
+		}
+	}
+
+	switch {
+	case quote != 0:
+		return fmt.Errorf("unterminated string")
+	case len(stack) > 0:
+		return fmt.Errorf("unclosed %q", stack[len(stack)-1])
+	case expectName != 0:
+		return fmt.Errorf("%q at the end must be followed by a name", expectName)
+	case strings.ContainsRune(">+~,", lastSignificant):
+		return fmt.Errorf("dangling %q at the end", lastSignificant)
+	}
+	return nil
+}
+
+// isNameStart reports whether r can start the name after '#', '.' or '['.
+// CSS identifiers can't start with a digit, so "#123" is rejected.
+func isNameStart(r rune, after rune) bool {
+	if after == '[' && unicode.IsSpace(r) {
+		return true
+	}
+	return r == '_' || r == '-' || r == '\\' || unicode.IsLetter(r) || r > unicode.MaxASCII
+}
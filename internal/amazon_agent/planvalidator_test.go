@@ -0,0 +1,26 @@
+package amazon_agent
+
+import "testing"
+
+func TestPlanValidatorSteps(t *testing.T) {
+	v := NewPlanValidator(NewExecutor(nil, nil, &AgentMemory{}).Actions())
+
+	tests := []struct {
+		name  string
+		step  Step
+		valid bool
+	}{
+		{"search without value", Step{Action: "type", Target: "#twotabsearchtextbox", Description: "Search for the task's product"}, true},
+		{"type with value", Step{Action: "type", Target: "#twotabsearchtextbox", Value: "detergent"}, true},
+		{"type without target", Step{Action: "type", Value: "detergent"}, false},
+		{"alias", Step{Action: "back"}, true},
+		{"unknown action", Step{Action: "search", Value: "detergent"}, false},
+		{"relative URL", Step{Action: "navigate", Target: "/s?k=detergent"}, false},
+	}
+	for _, tc := range tests {
+		err := v.Validate(&Plan{Steps: []Step{tc.step}})
+		if (err == nil) != tc.valid {
+			t.Errorf("%s: Validate = %v, want valid %v", tc.name, err, tc.valid)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package amazon_agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SearchResult is one product of a search results page.
+type SearchResult struct {
+	ASIN  string `json:"asin"`
+	Title string `json:"title"`
+	// Brand is the brand line some result cards show above the title.
+	Brand string `json:"brand,omitempty"`
+	URL   string `json:"url"`
+	// Price is in rupees; zero when the result shows no price.
+	Price float64 `json:"price,omitempty"`
+	// Rating is out of 5; zero when the product has no ratings yet.
+	Rating    float64 `json:"rating,omitempty"`
+	Reviews   int     `json:"reviews,omitempty"`
+	Prime     bool    `json:"prime,omitempty"`
+	Sponsored bool    `json:"sponsored,omitempty"`
+	// Position is the 1-based place of the result among all those
+	// collected, and Page the results page it was found on.
+	Position int `json:"position"`
+	Page     int `json:"page,omitempty"`
+}
+
+// searchResultsScript collects the product cards of a search results page,
+// falling back to bare product links on pages without result cards.
+const searchResultsScript = `
+() => {
+    const number = (text) => {
+        const m = (text || '').replace(/,/g, '').match(/\d+(\.\d+)?/);
+        return m ? parseFloat(m[0]) : 0;
+    };
+    const count = (text) => {
+        const m = (text || '').replace(/,/g, '').match(/(\d+(\.\d+)?)\s*([kKmM])?/);
+        if (!m) return 0;
+        const scale = { k: 1e3, m: 1e6 }[(m[3] || '').toLowerCase()] || 1;
+        return Math.round(parseFloat(m[1]) * scale);
+    };
+    const asinOf = (href) => {
+        const m = (href || '').match(/\/(?:dp|gp\/product)\/([A-Z0-9]{10})/);
+        return m ? m[1] : '';
+    };
+
+    const results = [];
+    const seen = new Set();
+    const cards = document.querySelectorAll('[data-component-type="s-search-result"], .s-result-item[data-asin]');
+    for (const card of cards) {
+        const link = card.querySelector('h2 a[href], a.s-no-outline[href*="/dp/"], a[href*="/dp/"]');
+        if (!link) continue;
+        const asin = card.getAttribute('data-asin') || asinOf(link.href);
+        if (!asin || seen.has(asin)) continue;
+        seen.add(asin);
+
+        const title = card.querySelector('h2');
+        const brand = card.querySelector('[data-cy="title-recipe"] > .a-row .a-size-base-plus, h5 .a-size-base-plus');
+        const price = card.querySelector('.a-price:not(.a-text-price) .a-offscreen, .a-price-whole');
+        const rating = card.querySelector('[aria-label*="out of 5 stars"], .a-icon-alt');
+        const reviews = card.querySelector('a[href*="customerReviews"], [aria-label$="ratings"], .s-underline-text');
+        const sponsored = card.querySelector('.puis-label-popover-default, .s-label-popover-default, .puis-sponsored-label-text, [aria-label="Sponsored"]');
+
+        results.push({
+            asin: asin,
+            title: ((title || link).innerText || '').trim(),
+            brand: brand ? brand.textContent.trim() : '',
+            url: link.href,
+            price: price ? number(price.textContent) : 0,
+            rating: rating ? number(rating.getAttribute('aria-label') || rating.textContent) : 0,
+            reviews: reviews ? count(reviews.getAttribute('aria-label') || reviews.textContent) : 0,
+            prime: !!card.querySelector('.a-icon-prime, [aria-label="Amazon Prime"]'),
+            sponsored: !!(sponsored && /sponsored/i.test(sponsored.textContent || sponsored.getAttribute('aria-label'))),
+            position: results.length + 1
+        });
+    }
+
+    if (results.length === 0) {
+        for (const link of document.querySelectorAll('a[href*="/dp/"]')) {
+            const asin = asinOf(link.href);
+            if (!asin || seen.has(asin)) continue;
+            seen.add(asin);
+            results.push({
+                asin: asin,
+                title: (link.innerText || link.getAttribute('title') || '').trim(),
+                url: link.href,
+                position: results.length + 1
+            });
+        }
+    }
+    return results;
+}
+`
+
+// extractSearchResults reads the products of the current search results
+// page.
+func (e *Executor) extractSearchResults(ctx context.Context) ([]SearchResult, error) {
+	raw, err := e.browser.Evaluate(ctx, searchResultsScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract products: %w", err)
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract products: %w", err)
+	}
+	var results []SearchResult
+	if err := json.Unmarshal(encoded, &results); err != nil {
+		return nil, fmt.Errorf("failed to extract products: %w", err)
+	}
+	return results, nil
+}
+
+// nextPageScript returns the URL of the next results page, or "" on the
+// last one.
+const nextPageScript = `
+() => {
+    const next = document.querySelector('a.s-pagination-next:not(.s-pagination-disabled), a[aria-label^="Go to next page"], ul.a-pagination li.a-last a');
+    return next ? next.href : '';
+}
+`
+
+// collectSearchResults reads the products of the current results page and
+// of the pages after it, following "Next" until pages pages are read, limit
+// products are collected or enough (if set) is satisfied. Products already
+// collected from an earlier page are skipped. The browser is taken back to
+// the page it started from before returning.
+func (e *Executor) collectSearchResults(ctx context.Context, pages, limit int, enough func([]SearchResult) bool) ([]SearchResult, error) {
+	var results []SearchResult
+	seen := make(map[string]bool)
+	start := e.browser.URL()
+	done := func() ([]SearchResult, error) {
+		if e.browser.URL() != start {
+			if err := e.browser.Navigate(ctx, start); err != nil {
+				e.logf("   ⚠️  Could not return to the first results page: %v\n", err)
+			} else {
+				e.waitForResults(ctx)
+			}
+		}
+		return results, nil
+	}
+	for page := 1; ; page++ {
+		found, err := e.extractSearchResults(ctx)
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for _, r := range found {
+			if seen[r.ASIN] || len(results) >= limit {
+				continue
+			}
+			seen[r.ASIN] = true
+			r.Page = page
+			r.Position = len(results) + 1
+			results = append(results, r)
+			added++
+		}
+		e.logf("   📄 Results page %d: %d products, %d new\n", page, len(found), added)
+
+		if page >= pages || len(results) >= limit || (enough != nil && enough(results)) {
+			return done()
+		}
+		next, err := e.browser.Evaluate(ctx, nextPageScript)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find next results page: %w", err)
+		}
+		nextURL, _ := next.(string)
+		if nextURL == "" {
+			return done()
+		}
+		if err := e.browser.Navigate(ctx, nextURL); err != nil {
+			e.logf("   ⚠️  Could not open results page %d: %v\n", page+1, err)
+			return done()
+		}
+		e.waitForResults(ctx)
+	}
+}
+
+// searchBounds is the page and product limit for collecting the results of
+// step: its "pages" and "limit" parameters, or the configured defaults.
+func (e *Executor) searchBounds(step Step) (pages, limit int, err error) {
+	pages, limit = e.searchPages, e.searchLimit
+	if n, ok, err := intParam(step, "pages"); err != nil {
+		return 0, 0, err
+	} else if ok {
+		pages = n
+	}
+	if n, ok, err := intParam(step, "limit"); err != nil {
+		return 0, 0, err
+	} else if ok {
+		limit = n
+	}
+	return pages, limit, nil
+}
+
+// rankResults returns the indexes of the results that pass the filters of
+// criteria, best first by its sort key (page order by default).
+func rankResults(results []SearchResult, criteria *ProductCriteria) []int {
+	var candidates []int
+	for i, r := range results {
+		if criteria.Matches(r) {
+			candidates = append(candidates, i)
+		}
+	}
+
+	switch criteria.Sort {
+	case SortPriceAsc, SortPriceDesc:
+		desc := criteria.Sort == SortPriceDesc
+		sort.SliceStable(candidates, func(a, b int) bool {
+			pa, pb := results[candidates[a]].Price, results[candidates[b]].Price
+			// Results without a price go last.
+			if pa == 0 || pb == 0 {
+				return pb == 0 && pa != 0
+			}
+			if desc {
+				return pa > pb
+			}
+			return pa < pb
+		})
+	case SortRating:
+		sort.SliceStable(candidates, func(a, b int) bool {
+			ra, rb := results[candidates[a]], results[candidates[b]]
+			if ra.Rating != rb.Rating {
+				return ra.Rating > rb.Rating
+			}
+			return ra.Reviews > rb.Reviews
+		})
+	case SortReviews:
+		sort.SliceStable(candidates, func(a, b int) bool {
+			return results[candidates[a]].Reviews > results[candidates[b]].Reviews
+		})
+	}
+	return candidates
+}
+
+// selectProductByCriteria picks the result criteria asks for, and says
+// why: the one at its position among the ranked results.
+func selectProductByCriteria(results []SearchResult, criteria *ProductCriteria) (int, string, error) {
+	if len(results) == 0 {
+		return 0, "", fmt.Errorf("no products found on page")
+	}
+
+	candidates := rankResults(results, criteria)
+	among := fmt.Sprintf("%d results", len(results))
+	if len(candidates) < len(results) {
+		among = fmt.Sprintf("%d of %d results matching", len(candidates), len(results))
+	}
+	if len(candidates) == 0 {
+		return 0, "", fmt.Errorf("no product matches %s (%d results checked)", criteria, len(results))
+	}
+
+	pick := max(criteria.Position, 1)
+	if pick > len(candidates) {
+		return 0, "", fmt.Errorf("no result #%d for %s: only %s", pick, criteria, among)
+	}
+
+	order := "in page order"
+	if criteria.Sort != SortRelevance && criteria.Sort != SortNewest {
+		order = "by " + sortDescriptions[criteria.Sort]
+	}
+	selected := results[candidates[pick-1]]
+	reason := fmt.Sprintf("#%d %s among %s (%s)", pick, order, among, describeResult(selected))
+	if selected.Page > 1 {
+		reason += fmt.Sprintf(", found on results page %d", selected.Page)
+	}
+	return candidates[pick-1], reason, nil
+}
+
+// describeResult summarizes the price, rating and badges of r.
+func describeResult(r SearchResult) string {
+	var parts []string
+	if r.Price > 0 {
+		parts = append(parts, formatRupees(r.Price))
+	}
+	if r.Rating > 0 {
+		parts = append(parts, fmt.Sprintf("%.1f★ from %d reviews", r.Rating, r.Reviews))
+	}
+	if r.Prime {
+		parts = append(parts, "Prime")
+	}
+	if r.Sponsored {
+		parts = append(parts, "sponsored")
+	}
+	if len(parts) == 0 {
+		return "no price or rating shown"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatRupees(v float64) string {
+	return "₹" + strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,35 @@
+package amazon_agent
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRankResultsByPrice(t *testing.T) {
+	// Equal prices and missing ones keep page order, whichever the
+	// direction.
+	results := []SearchResult{
+		{ASIN: "A", Price: 300},
+		{ASIN: "B", Price: 0},
+		{ASIN: "C", Price: 100},
+		{ASIN: "D", Price: 300},
+		{ASIN: "E", Price: 200},
+		{ASIN: "F", Price: 0},
+	}
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{SortPriceAsc, []string{"C", "E", "A", "D", "B", "F"}},
+		{SortPriceDesc, []string{"A", "D", "E", "C", "B", "F"}},
+	}
+	for _, tc := range tests {
+		var got []string
+		for _, i := range rankResults(results, &ProductCriteria{Sort: tc.sort}) {
+			got = append(got, results[i].ASIN)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.sort, got, tc.want)
+		}
+	}
+}
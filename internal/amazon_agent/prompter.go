@@ -0,0 +1,99 @@
+package amazon_agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// Prompter supplies user input such as credentials and address fields.
+// field is a stable key ("email", "password", "pincode", ...); label is the
+// text shown to a human.
+type Prompter interface {
+	Prompt(ctx context.Context, field, label string) (string, error)
+	PromptSecret(ctx context.Context, field, label string) (string, error)
+}
+
+// TerminalPrompter reads answers from stdin, hiding secrets. Prompts are
+// written to stderr so they never mix with a JSON event stream on stdout.
+// A pending prompt returns ctx.Err() as soon as ctx is cancelled.
+type TerminalPrompter struct {
+	reader *bufio.Reader
+}
+
+func NewTerminalPrompter() *TerminalPrompter {
+	return &TerminalPrompter{reader: bufio.NewReader(os.Stdin)}
+}
+
+type readResult struct {
+	text string
+	err  error
+}
+
+func (p *TerminalPrompter) Prompt(ctx context.Context, field, label string) (string, error) {
+	fmt.Fprint(os.Stderr, label)
+
+	done := make(chan readResult, 1)
+	go func() {
+		input, err := p.reader.ReadString('\n')
+		done <- readResult{input, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil && r.text == "" {
+			return "", fmt.Errorf("read %s: %w", field, r.err)
+		}
+		return strings.TrimSpace(r.text), nil
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr)
+		return "", ctx.Err()
+	}
+}
+
+func (p *TerminalPrompter) PromptSecret(ctx context.Context, field, label string) (string, error) {
+	fd := int(syscall.Stdin)
+	fmt.Fprint(os.Stderr, label)
+
+	// ReadPassword only restores echo when it returns, so keep the original
+	// state around for the cancellation path.
+	oldState, _ := term.GetState(fd)
+
+	done := make(chan readResult, 1)
+	go func() {
+		secret, err := term.ReadPassword(fd)
+		done <- readResult{string(secret), err}
+	}()
+
+	select {
+	case r := <-done:
+		fmt.Fprintln(os.Stderr) // New line after hidden input
+		if r.err != nil {
+			return "", fmt.Errorf("read %s: %w", field, r.err)
+		}
+		return r.text, nil
+	case <-ctx.Done():
+		if oldState != nil {
+			term.Restore(fd, oldState)
+		}
+		fmt.Fprintln(os.Stderr)
+		return "", ctx.Err()
+	}
+}
+
+// StaticPrompter answers every prompt from a fixed map, for unattended runs.
+// Unknown fields are answered with an empty string.
+type StaticPrompter map[string]string
+
+func (p StaticPrompter) Prompt(ctx context.Context, field, label string) (string, error) {
+	return p[field], ctx.Err()
+}
+
+func (p StaticPrompter) PromptSecret(ctx context.Context, field, label string) (string, error) {
+	return p[field], ctx.Err()
+}
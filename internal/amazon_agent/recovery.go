@@ -0,0 +1,209 @@
+package amazon_agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"browser-agent/internal/browser"
+)
+
+// maxRecoveries bounds the recoveries of one run so strategies that move
+// the run backwards can't loop until the total timeout.
+const maxRecoveries = 5
+
+// RecoveryStrategy gets a run unstuck after several steps in a row have
+// failed. Strategies are tried in the order of Config.RecoveryStrategies
+// until one returns a Recovery.
+type RecoveryStrategy interface {
+	Name() string
+	// Recover returns where to continue, or an error if the strategy could
+	// not recover. A nil Recovery and nil error means the strategy gives
+	// up on recovery altogether; no further strategies are tried.
+	Recover(ctx context.Context, rc *RecoveryContext) (*Recovery, error)
+}
+
+// RecoveryContext is what a strategy knows about the failure.
+type RecoveryContext struct {
+	Browser   *browser.Browser
+	Planner   *Planner
+	Execution *ExecutionContext
+	// FailedStep is the index of the last failed step in Execution.Plan.
+	FailedStep int
+	Err        error
+	// ResumeStep and ResumeURL are the last checkpointed position that
+	// followed a successful step, and the page the browser was on then.
+	ResumeStep int
+	ResumeURL  string
+}
+
+// Recovery tells the step loop how to continue.
+type Recovery struct {
+	// Plan replaces the current plan when set.
+	Plan *Plan
+	// NextStep is the index of the step to continue with.
+	NextStep int
+	Detail   string
+}
+
+// RecoveryAttempt records one strategy tried during a run.
+type RecoveryAttempt struct {
+	Strategy string    `json:"strategy"`
+	Step     int       `json:"step"`
+	Reason   string    `json:"reason"`
+	Success  bool      `json:"success"`
+	Detail   string    `json:"detail,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Plan     *Plan     `json:"plan,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+var recoveryStrategies = map[string]func() RecoveryStrategy{
+	"none":                    func() RecoveryStrategy { return noRecovery{} },
+	"reload_and_retry":        func() RecoveryStrategy { return reloadAndRetry{} },
+	"go_back":                 func() RecoveryStrategy { return goBack{} },
+	"llm_plan":                func() RecoveryStrategy { return llmRecoveryPlan{} },
+	"restart_from_checkpoint": func() RecoveryStrategy { return restartFromCheckpoint{} },
+}
+
+// RecoveryStrategiesByName returns the built-in strategies for names.
+func RecoveryStrategiesByName(names []string) ([]RecoveryStrategy, error) {
+	var strategies []RecoveryStrategy
+	for _, name := range names {
+		newStrategy, ok := recoveryStrategies[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown recovery strategy %q (want none, reload_and_retry, go_back, llm_plan or restart_from_checkpoint)", name)
+		}
+		strategies = append(strategies, newStrategy())
+	}
+	return strategies, nil
+}
+
+// SetRecoveryStrategies replaces the strategies configured by
+// Config.RecoveryStrategies.
+func (a *Agent) SetRecoveryStrategies(strategies ...RecoveryStrategy) {
+	a.recovery = strategies
+}
+
+// recover tries the configured strategies in order and returns the first
+// successful recovery, or nil. Every attempt is recorded on the run.
+func (a *Agent) recover(ctx context.Context, rc *RecoveryContext) *Recovery {
+	recovered := 0
+	for _, attempt := range a.recoveries {
+		if attempt.Success {
+			recovered++
+		}
+	}
+	if recovered >= maxRecoveries {
+		a.logf("   ⚠️  Recovery limit of %d reached, continuing without recovery\n", maxRecoveries)
+		return nil
+	}
+
+	for _, strategy := range a.recovery {
+		recovery, err := strategy.Recover(ctx, rc)
+		attempt := RecoveryAttempt{
+			Strategy: strategy.Name(),
+			Step:     rc.FailedStep + 1,
+			Reason:   errString(rc.Err),
+			Success:  err == nil && recovery != nil,
+			Error:    errString(err),
+			Time:     time.Now(),
+		}
+		if recovery != nil {
+			attempt.Detail = recovery.Detail
+			attempt.Plan = recovery.Plan
+		} else if err == nil {
+			attempt.Detail = "recovery disabled"
+		}
+		a.recoveries = append(a.recoveries, attempt)
+		a.emit(Event{Type: EventRecovery, Recovery: &attempt})
+
+		switch {
+		case attempt.Success:
+			return recovery
+		case err == nil, ctx.Err() != nil:
+			return nil
+		}
+	}
+	return nil
+}
+
+type noRecovery struct{}
+
+func (noRecovery) Name() string { return "none" }
+
+func (noRecovery) Recover(ctx context.Context, rc *RecoveryContext) (*Recovery, error) {
+	return nil, nil
+}
+
+// reloadAndRetry reloads the page and runs the failed step again.
+type reloadAndRetry struct{}
+
+func (reloadAndRetry) Name() string { return "reload_and_retry" }
+
+func (reloadAndRetry) Recover(ctx context.Context, rc *RecoveryContext) (*Recovery, error) {
+	if err := rc.Browser.Reload(ctx); err != nil {
+		return nil, fmt.Errorf("reload: %w", err)
+	}
+	return &Recovery{
+		NextStep: rc.FailedStep,
+		Detail:   fmt.Sprintf("reloaded %s, retrying step %d", rc.Browser.URL(), rc.FailedStep+1),
+	}, nil
+}
+
+// goBack returns to the previous page and runs the failed step again, for
+// when a step navigated somewhere unexpected.
+type goBack struct{}
+
+func (goBack) Name() string { return "go_back" }
+
+func (goBack) Recover(ctx context.Context, rc *RecoveryContext) (*Recovery, error) {
+	if err := rc.Browser.GoBack(ctx); err != nil {
+		return nil, fmt.Errorf("go back: %w", err)
+	}
+	return &Recovery{
+		NextStep: rc.FailedStep,
+		Detail:   fmt.Sprintf("went back to %s, retrying step %d", rc.Browser.URL(), rc.FailedStep+1),
+	}, nil
+}
+
+// llmRecoveryPlan asks the planner for a new plan from the current page.
+type llmRecoveryPlan struct{}
+
+func (llmRecoveryPlan) Name() string { return "llm_plan" }
+
+func (llmRecoveryPlan) Recover(ctx context.Context, rc *RecoveryContext) (*Recovery, error) {
+	pageState, err := rc.Browser.GetPageState(ctx)
+	if err != nil {
+		pageState = &browser.PageState{}
+	}
+	plan, err := rc.Planner.CreateRecoveryPlan(ctx, rc.Execution, pageState, errString(rc.Err))
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil || len(plan.Steps) == 0 {
+		return nil, errors.New("recovery plan has no steps")
+	}
+	return &Recovery{Plan: plan, Detail: fmt.Sprintf("new plan with %d steps", len(plan.Steps))}, nil
+}
+
+// restartFromCheckpoint returns to the page of the last checkpoint that
+// followed a successful step and continues from there.
+type restartFromCheckpoint struct{}
+
+func (restartFromCheckpoint) Name() string { return "restart_from_checkpoint" }
+
+func (restartFromCheckpoint) Recover(ctx context.Context, rc *RecoveryContext) (*Recovery, error) {
+	if rc.ResumeURL == "" || strings.HasPrefix(rc.ResumeURL, "about:") {
+		return nil, errors.New("no checkpointed page to restart from")
+	}
+	if err := rc.Browser.Navigate(ctx, rc.ResumeURL); err != nil {
+		return nil, fmt.Errorf("navigate to %s: %w", rc.ResumeURL, err)
+	}
+	return &Recovery{
+		NextStep: rc.ResumeStep,
+		Detail:   fmt.Sprintf("restarted from step %d at %s", rc.ResumeStep+1, rc.ResumeURL),
+	}, nil
+}
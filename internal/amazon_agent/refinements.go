@@ -0,0 +1,347 @@
+package amazon_agent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"browser-agent/internal/browser"
+)
+
+// refinement is one search filter, applied through the results page's
+// sidebar when it offers it and through the rh URL parameter otherwise.
+type refinement struct {
+	// kind and arg are passed to refineScript; label is for the log.
+	kind  string
+	arg   interface{}
+	label string
+	// rh is the equivalent entry of Amazon's rh parameter, such as
+	// "p_85:10440599031"; empty when there is none.
+	rh string
+}
+
+// Refinement ids of amazon.in's rh parameter: p_36 is a price range in
+// paise, p_72 a minimum customer review, p_85 Prime, p_89 a brand and
+// p_90 a delivery day.
+var (
+	ratingNodes = map[int]string{
+		4: "1318476031",
+		3: "1318477031",
+		2: "1318478031",
+		1: "1318479031",
+	}
+	primeNode    = "10440599031"
+	tomorrowNode = "6741118031"
+)
+
+var deliveryPattern = regexp.MustCompile(`(?i)\b(?:(?:get\s+it|delivery|delivered|arriving)\s+)?(?:by\s+)?(today|tomorrow)\b|\b(same|next)[\s-]+day(?:\s+delivery)?\b`)
+
+// parseRefinements turns apply_filter's value, such as "4 stars & up,
+// Prime, under ₹2000, brand Logitech, delivery tomorrow", into the
+// refinements to apply. It accepts the criteria select_product does, of
+// which only those the site can filter by are used.
+func parseRefinements(value string) ([]refinement, *ProductCriteria, error) {
+	var refinements []refinement
+	rest := deliveryPattern.ReplaceAllStringFunc(value, func(match string) string {
+		m := deliveryPattern.FindStringSubmatch(match)
+		day := strings.ToLower(m[1])
+		if day == "" {
+			day = map[string]string{"same": "today", "next": "tomorrow"}[strings.ToLower(m[2])]
+		}
+		r := refinement{kind: "delivery", arg: day, label: "Get it " + day}
+		if day == "tomorrow" {
+			r.rh = "p_90:" + tomorrowNode
+		}
+		refinements = append(refinements, r)
+		return " , "
+	})
+
+	spec := ParseProductCriteria(rest)
+	if label := spec.priceRange(); label != "" {
+		refinements = append(refinements, refinement{
+			kind:  "price",
+			arg:   map[string]float64{"low": spec.MinPrice, "high": spec.MaxPrice},
+			label: label,
+			rh:    fmt.Sprintf("p_36:%s-%s", paise(spec.MinPrice), paise(spec.MaxPrice)),
+		})
+	}
+	if spec.MinRating > 0 {
+		// The site only offers whole stars; a finer minimum is rounded down
+		// to the nearest one it offers.
+		stars := min(4, max(1, int(math.Floor(spec.MinRating))))
+		refinements = append(refinements, refinement{
+			kind:  "rating",
+			arg:   stars,
+			label: fmt.Sprintf("%d Stars & Up", stars),
+			rh:    "p_72:" + ratingNodes[stars],
+		})
+	}
+	if spec.PrimeOnly {
+		refinements = append(refinements, refinement{kind: "prime", label: "Prime", rh: "p_85:" + primeNode})
+	}
+	for _, brand := range spec.Brands {
+		// An order or filler word read as a brand would filter out every
+		// result.
+		if !isBrand(brand) {
+			spec.Ignored = append(spec.Ignored, brand)
+			continue
+		}
+		refinements = append(refinements, refinement{kind: "brand", arg: brand, label: "Brand " + brand, rh: "p_89:" + brand})
+	}
+
+	if len(refinements) == 0 {
+		return nil, spec, fmt.Errorf("no filter in %q (want a price range, rating, Prime, brand or delivery day)", value)
+	}
+	return refinements, spec, nil
+}
+
+func paise(rupees float64) string {
+	if rupees <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(rupees*100), 10)
+}
+
+// refineScript applies one refinement through the results page's sidebar
+// and reports whether it found the control to do so. Links are followed
+// after the script returns, so the evaluation isn't cut short by the
+// navigation.
+const refineScript = `
+(kind, arg) => {
+    const norm = (s) => (s || '').replace(/\s+/g, ' ').trim().toLowerCase();
+    const follow = (el) => { setTimeout(() => el.click(), 0); return true; };
+    const link = (selectors, test) => {
+        for (const el of document.querySelectorAll(selectors)) {
+            const text = norm(el.getAttribute('aria-label') || el.innerText || el.textContent);
+            const icon = el.querySelector('[aria-label]');
+            if (test(text) || (icon && test(norm(icon.getAttribute('aria-label'))))) return el;
+        }
+        return null;
+    };
+    let el = null;
+    switch (kind) {
+    case 'rating':
+        el = link('#reviewsRefinements a, [id^="p_72"] a', (t) => t.startsWith(arg + ' star') && t.includes('& up'));
+        break;
+    case 'prime':
+        el = link('#primeRefinements a, [id^="p_85"] a', (t) => t.includes('prime'));
+        break;
+    case 'brand':
+        el = link('#brandsRefinements a, [id^="p_89"] a, [id^="p_123"] a', (t) => t === norm(arg) || t === 'apply the filter ' + norm(arg) + ' to narrow results');
+        break;
+    case 'delivery':
+        el = link('#deliveryRefinements a, [id^="p_90"] a', (t) => t.includes(arg));
+        break;
+    case 'price': {
+        const low = document.querySelector('#priceRefinements input[name="low-price"], input#low-price');
+        const high = document.querySelector('#priceRefinements input[name="high-price"], input#high-price');
+        if (!low || !high) return false;
+        low.value = arg.low > 0 ? String(arg.low) : '';
+        high.value = arg.high > 0 ? String(arg.high) : '';
+        const form = high.form || low.form;
+        if (!form) return false;
+        setTimeout(() => form.requestSubmit ? form.requestSubmit() : form.submit(), 0);
+        return true;
+    }
+    }
+    return el ? follow(el) : false;
+}
+`
+
+// executeApplyFilter narrows the search results by price, rating, Prime,
+// brand or delivery day, using the site's own refinements.
+func (e *Executor) executeApplyFilter(ctx context.Context, step Step) (*ExecutionResult, error) {
+	refinements, spec, err := parseRefinements(step.GetValueString())
+	if err != nil {
+		return nil, err
+	}
+	if len(spec.Ignored) > 0 {
+		e.logf("   ⚠️  Not understood, ignored: %s\n", strings.Join(spec.Ignored, " "))
+	}
+
+	var applied, viaURL, urlLabels []string
+	for _, r := range refinements {
+		clicked, err := e.refineFromSidebar(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		if clicked {
+			e.logf("   🎚️  %s: applied from the sidebar\n", r.label)
+			applied = append(applied, r.label)
+			continue
+		}
+		if r.rh == "" {
+			return nil, fmt.Errorf("could not apply filter %q: not offered on this page", r.label)
+		}
+		viaURL = append(viaURL, r.rh)
+		urlLabels = append(urlLabels, r.label)
+		applied = append(applied, r.label)
+	}
+
+	if len(viaURL) > 0 {
+		target, err := withRefinements(e.browser.URL(), viaURL)
+		if err != nil {
+			return nil, err
+		}
+		e.logf("   🎚️  %s: applied through the URL (rh=%s)\n", strings.Join(urlLabels, ", "), strings.Join(viaURL, ","))
+		if err := e.browser.Navigate(ctx, target); err != nil {
+			return nil, fmt.Errorf("failed to open filtered results: %w", err)
+		}
+		e.waitForResults(ctx)
+	}
+
+	return &ExecutionResult{
+		Success: true,
+		Message: fmt.Sprintf("Filtered results: %s", strings.Join(applied, ", ")),
+		Data:    map[string]interface{}{"current_page": e.browser.URL()},
+	}, nil
+}
+
+// refineFromSidebar applies r through the sidebar if the page has the
+// control for it, waiting for the refined results to load.
+func (e *Executor) refineFromSidebar(ctx context.Context, r refinement) (bool, error) {
+	before := e.browser.URL()
+	result, err := e.evaluateWith(ctx, refineScript, r.kind, r.arg)
+	if err != nil {
+		return false, fmt.Errorf("failed to look for the %s filter: %w", r.kind, err)
+	}
+	if clicked, _ := result.(bool); !clicked {
+		return false, nil
+	}
+	for n := 0; n < 20 && e.browser.URL() == before; n++ {
+		if err := browser.Sleep(ctx, 250*time.Millisecond); err != nil {
+			return false, err
+		}
+	}
+	e.waitForResults(ctx)
+	return true, nil
+}
+
+func (e *Executor) waitForResults(ctx context.Context) {
+	e.browser.WaitForSelector(ctx, `[data-component-type="s-search-result"]`, 10*time.Second)
+}
+
+// withRefinements adds entries to the rh parameter of a results URL,
+// replacing any entry of the same refinement, and returns to the first
+// page.
+func withRefinements(rawURL string, entries []string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("current page %q: %w", rawURL, err)
+	}
+	q := u.Query()
+	if q.Get("k") == "" && q.Get("field-keywords") == "" {
+		return "", fmt.Errorf("not on a search results page (%s)", rawURL)
+	}
+	return setSearchParams(u, q, func(q url.Values) {
+		rh := entries
+		for _, part := range strings.Split(q.Get("rh"), ",") {
+			key, _, _ := strings.Cut(part, ":")
+			if part != "" && !hasRefinement(entries, key) {
+				rh = append(rh, part)
+			}
+		}
+		q.Set("rh", strings.Join(rh, ","))
+	}), nil
+}
+
+func hasRefinement(entries []string, key string) bool {
+	for _, entry := range entries {
+		if strings.HasPrefix(entry, key+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// setSearchParams applies change to the query of a results URL, dropping
+// the page number so the new results start from the first page.
+func setSearchParams(u *url.URL, q url.Values, change func(url.Values)) string {
+	change(q)
+	q.Del("page")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// resultOrders are the sort orders of sort_results, with the values of the
+// results page's sort dropdown (the s URL parameter).
+var resultOrders = []struct {
+	key, value, label string
+	pattern           *regexp.Regexp
+}{
+	{SortPriceAsc, "price-asc-rank", "Price: Low to High", regexp.MustCompile(`(?i)low\s*(?:to|-)\s*high|ascending|\bcheapest\b|lowest|price[\s_-]*asc`)},
+	{SortPriceDesc, "price-desc-rank", "Price: High to Low", regexp.MustCompile(`(?i)high\s*(?:to|-)\s*low|descending|most\s+expensive|highest\s+price|price[\s_-]*desc`)},
+	{SortRating, "review-rank", "Avg. Customer Review", regexp.MustCompile(`(?i)review|rating|rated|stars`)},
+	{SortNewest, "date-desc-rank", "Newest Arrivals", regexp.MustCompile(`(?i)newest|latest|new\s+arrivals|date`)},
+	{"featured", "relevanceblender", "Featured", regexp.MustCompile(`(?i)featured|relevan|default`)},
+}
+
+// sortScript picks an order in the results page's sort dropdown and
+// reports whether the page has one offering it.
+const sortScript = `
+(value) => {
+    const select = document.querySelector('#s-result-sort-select, select[name="s"]');
+    if (!select || !Array.from(select.options).some((o) => o.value === value)) return false;
+    select.value = value;
+    setTimeout(() => {
+        select.dispatchEvent(new Event('change', { bubbles: true }));
+    }, 0);
+    return true;
+}
+`
+
+// executeSortResults orders the search results with the site's sort
+// dropdown, or its s URL parameter when the page has no dropdown.
+func (e *Executor) executeSortResults(ctx context.Context, step Step) (*ExecutionResult, error) {
+	value := step.GetValueString()
+	i := 0
+	for i < len(resultOrders) && !resultOrders[i].pattern.MatchString(value) {
+		i++
+	}
+	if i == len(resultOrders) {
+		return nil, fmt.Errorf("unknown sort order %q (want price low to high, price high to low, avg. customer review, newest or featured)", value)
+	}
+	order := resultOrders[i]
+
+	before := e.browser.URL()
+	result, err := e.browser.Evaluate(ctx, fmt.Sprintf("(%s)(%q)", strings.TrimSpace(sortScript), order.value))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look for the sort dropdown: %w", err)
+	}
+	if picked, _ := result.(bool); picked {
+		for n := 0; n < 20 && e.browser.URL() == before; n++ {
+			if err := browser.Sleep(ctx, 250*time.Millisecond); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if e.browser.URL() != before {
+		e.logf("   ↕️  Sorted by %s from the dropdown\n", order.label)
+	} else {
+		u, err := url.Parse(before)
+		if err != nil {
+			return nil, fmt.Errorf("current page %q: %w", before, err)
+		}
+		q := u.Query()
+		if q.Get("k") == "" && q.Get("field-keywords") == "" {
+			return nil, fmt.Errorf("not on a search results page (%s)", before)
+		}
+		target := setSearchParams(u, q, func(q url.Values) { q.Set("s", order.value) })
+		e.logf("   ↕️  Sorted by %s through the URL (s=%s)\n", order.label, order.value)
+		if err := e.browser.Navigate(ctx, target); err != nil {
+			return nil, fmt.Errorf("failed to open sorted results: %w", err)
+		}
+	}
+	e.waitForResults(ctx)
+
+	return &ExecutionResult{
+		Success: true,
+		Message: fmt.Sprintf("Sorted results by %s", order.label),
+		Data:    map[string]interface{}{"current_page": e.browser.URL()},
+	}, nil
+}
@@ -0,0 +1,43 @@
+package amazon_agent
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseRefinements(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string // the rh entries
+	}{
+		{"4 stars & up", []string{"p_72:1318476031"}},
+		{"4 stars & up, sorted by relevance", []string{"p_72:1318476031"}},
+		{"Prime, by newest", []string{"p_85:10440599031"}},
+		{"under ₹2000, brand Logitech", []string{"p_36:-200000", "p_89:Logitech"}},
+		{"from Sony or JBL, delivery tomorrow", []string{"p_90:6741118031", "p_89:Sony", "p_89:JBL"}},
+		{"3.5 stars, sort by avg. customer review", []string{"p_72:1318477031"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			refinements, _, err := parseRefinements(tc.value)
+			if err != nil {
+				t.Fatalf("parseRefinements: %v", err)
+			}
+			var got []string
+			for _, r := range refinements {
+				got = append(got, r.rh)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("rh = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseRefinementsWithoutFilter(t *testing.T) {
+	for _, value := range []string{"sorted by relevance", "by customer reviews", "cheapest"} {
+		if refinements, _, err := parseRefinements(value); err == nil {
+			t.Errorf("parseRefinements(%q) = %v, want an error: it has no filter", value, refinements)
+		}
+	}
+}
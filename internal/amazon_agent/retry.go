@@ -0,0 +1,154 @@
+package amazon_agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"browser-agent/internal/browser"
+)
+
+// RetryPolicy is the deadline and retry schedule for the steps of one
+// action, resolved from Config and its per-action overrides.
+type RetryPolicy struct {
+	// Timeout bounds each attempt; zero means no deadline.
+	Timeout time.Duration
+	// MaxRetries is how many times a failed critical step is retried.
+	MaxRetries int
+	// Delay is the wait before the first retry; each later one doubles it,
+	// up to MaxDelay.
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// retryPolicy resolves the policy for action: the global settings with the
+// overrides of the action applied. Overrides may be keyed by the action's
+// registered name or any of its aliases; the registered name wins where
+// both set a field.
+func (a *Agent) retryPolicy(action string) RetryPolicy {
+	policy := RetryPolicy{
+		Timeout:    a.config.StepTimeout,
+		MaxRetries: a.config.MaxRetries,
+		Delay:      a.config.RetryDelay,
+		MaxDelay:   a.config.MaxRetryDelay,
+	}
+	keys := []string{action}
+	if registered, ok := a.executor.Actions().Lookup(action); ok {
+		keys = append(slices.Clone(registered.Schema().Aliases), registered.Name())
+	}
+	for _, key := range keys {
+		override, ok := a.config.Actions[key]
+		if !ok {
+			continue
+		}
+		if override.StepTimeout != nil {
+			policy.Timeout = *override.StepTimeout
+		}
+		if override.MaxRetries != nil {
+			policy.MaxRetries = *override.MaxRetries
+		}
+		if override.RetryDelay != nil {
+			policy.Delay = *override.RetryDelay
+		}
+	}
+	return policy
+}
+
+// Backoff is the wait before the given retry (1-based): Delay doubled for
+// each earlier retry, capped at MaxDelay, with ±25% jitter so that retries
+// against a struggling page don't fall into lockstep.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	if p.Delay <= 0 {
+		return 0
+	}
+	d := p.Delay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return time.Duration(float64(d) * (0.75 + rand.Float64()/2))
+}
+
+// attemptStep runs one attempt of step under timeout. An attempt that runs
+// out of time fails with a "step timed out" error; the cancellation of the
+// run itself is passed through unchanged.
+func (a *Agent) attemptStep(runCtx context.Context, step Step, executionContext *ExecutionContext, timeout time.Duration) (*ExecutionResult, error) {
+	ctx := runCtx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(runCtx, timeout)
+		defer cancel()
+	}
+
+	result, err := a.executor.ExecuteStep(ctx, step, executionContext)
+	if err != nil && runCtx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("step timed out after %s: %w", timeout, err)
+	}
+	return result, err
+}
+
+// retryCriticalStep retries a failed critical step according to its
+// action's policy, recording each attempt on the step's ExecutedStep (the
+// last entry of executionContext.ExecutedSteps). It returns the result of
+// the last attempt. An add_to_cart that clicked before failing is only
+// retried when the cart count shows the click added nothing.
+func (a *Agent) retryCriticalStep(runCtx context.Context, executionContext *ExecutionContext, stepNum, stepTotal, seq int, err error) (*ExecutionResult, error) {
+	executedStep := &executionContext.ExecutedSteps[len(executionContext.ExecutedSteps)-1]
+	step := executedStep.Step
+	policy := a.retryPolicy(step.Action)
+
+	var result *ExecutionResult
+	for retry := 1; err != nil && retry <= policy.MaxRetries; retry++ {
+		delay := policy.Backoff(retry)
+		a.logf("   🔄 Retrying critical step in %s (retry %d/%d)...\n", delay.Round(100*time.Millisecond), retry, policy.MaxRetries)
+		if browser.Sleep(runCtx, delay) != nil {
+			return nil, runCtx.Err()
+		}
+
+		// Adding to the cart again would double a line the failed attempt
+		// already added.
+		added, settleErr := a.executor.settlePendingAdd(runCtx)
+		if settleErr != nil {
+			a.logf("   ⚠️  Not retrying: %v\n", settleErr)
+			return result, fmt.Errorf("%w (not retried: %v)", err, settleErr)
+		}
+		if added != nil {
+			executedStep.Success = true
+			executedStep.Error = nil
+			executedStep.Timestamp = time.Now()
+			return added, nil
+		}
+
+		retryStart := time.Now()
+		result, err = a.attemptStep(runCtx, step, executionContext, policy.Timeout)
+		retryDuration := time.Since(retryStart)
+
+		retryArtifacts := a.stepArtifacts(seq, nil, fmt.Sprintf("retry%d-%s", retry, ArtifactAfter), err)
+		executedStep.Artifacts = append(executedStep.Artifacts, retryArtifacts...)
+		executedStep.Retries = retry
+		executedStep.Success = err == nil
+		executedStep.Error = err
+		executedStep.Timestamp = time.Now()
+
+		a.emit(Event{Type: EventStepFinished, Step: &StepEvent{
+			Number:    stepNum,
+			Total:     stepTotal,
+			Step:      step,
+			Attempt:   retry + 1,
+			Success:   err == nil,
+			Stopped:   runCtx.Err() != nil,
+			Error:     errString(err),
+			Duration:  retryDuration,
+			Artifacts: retryArtifacts,
+		}})
+		if runCtx.Err() != nil {
+			return nil, runCtx.Err()
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,40 @@
+package amazon_agent
+
+import (
+	"testing"
+	"time"
+
+	"browser-agent/internal/config"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	cfg := config.NewConfig()
+	for key, value := range map[string]string{
+		"actions.navigate.step_timeout": "0",
+		"actions.back.max_retries":      "7",
+		"actions.back.retry_delay":      "1s",
+		"actions.go_back.retry_delay":   "3s",
+	} {
+		if err := cfg.Set(key, value, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := &Agent{config: cfg, executor: NewExecutor(nil, nil, &AgentMemory{})}
+
+	tests := []struct {
+		action string
+		want   RetryPolicy
+	}{
+		{"click", RetryPolicy{Timeout: cfg.StepTimeout, MaxRetries: cfg.MaxRetries, Delay: cfg.RetryDelay, MaxDelay: cfg.MaxRetryDelay}},
+		// An explicit zero lifts the deadline instead of inheriting it.
+		{"navigate", RetryPolicy{Timeout: 0, MaxRetries: cfg.MaxRetries, Delay: cfg.RetryDelay, MaxDelay: cfg.MaxRetryDelay}},
+		// Keys given as an alias apply, with the registered name winning.
+		{"go_back", RetryPolicy{Timeout: cfg.StepTimeout, MaxRetries: 7, Delay: 3 * time.Second, MaxDelay: cfg.MaxRetryDelay}},
+		{"back", RetryPolicy{Timeout: cfg.StepTimeout, MaxRetries: 7, Delay: 3 * time.Second, MaxDelay: cfg.MaxRetryDelay}},
+	}
+	for _, tc := range tests {
+		if got := a.retryPolicy(tc.action); got != tc.want {
+			t.Errorf("retryPolicy(%q) = %+v, want %+v", tc.action, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package amazon_agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+var sessionNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// SessionPath returns where the named session profile is stored in dir.
+// Names are plain file names so a profile can never escape dir.
+func SessionPath(dir, name string) (string, error) {
+	if !sessionNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid session name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// existingSession resolves the configured profile and reports whether it
+// has been saved before.
+func existingSession(dir, name string) (path string, exists bool, err error) {
+	path, err = SessionPath(dir, name)
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return path, false, nil
+		}
+		return "", false, fmt.Errorf("session %s: %w", name, err)
+	}
+	return path, true, nil
+}
+
+// saveSession writes the browser's cookies and localStorage back to the
+// session profile. Like checkpoints it ignores the run's context, so an
+// interrupted run still keeps a fresh login.
+func (a *Agent) saveSession() {
+	if a.sessionPath == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := os.MkdirAll(filepath.Dir(a.sessionPath), 0o700); err != nil {
+		a.logf("   ⚠️  Could not save session %s: %v\n", a.config.Session, err)
+		return
+	}
+	if err := a.browser.SaveStorageState(ctx, a.sessionPath); err != nil {
+		a.logf("   ⚠️  Could not save session %s: %v\n", a.config.Session, err)
+	}
+}
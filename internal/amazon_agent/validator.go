@@ -1,6 +1,7 @@
 package amazon_agent
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -10,7 +11,7 @@ import (
 )
 
 type Validator struct {
-	llm *llm.GeminiClient
+	llm llm.Client
 }
 
 type ValidationResult struct {
@@ -21,14 +22,14 @@ type ValidationResult struct {
 	CurrentPhase    string  `json:"current_phase"`
 }
 
-func NewValidator(llmClient *llm.GeminiClient) *Validator {
+func NewValidator(llmClient llm.Client) *Validator {
 	return &Validator{llm: llmClient}
 }
 
-func (v *Validator) ValidateProgress(ctx *ExecutionContext, pageState *browser.PageState) (*ValidationResult, error) {
+func (v *Validator) ValidateProgress(ctx context.Context, execCtx *ExecutionContext, pageState *browser.PageState) (*ValidationResult, error) {
 	executedStepsDesc := ""
 	successCount := 0
-	for i, step := range ctx.ExecutedSteps {
+	for i, step := range execCtx.ExecutedSteps {
 		status := "✓"
 		if !step.Success {
 			status = "✗"
@@ -39,8 +40,8 @@ func (v *Validator) ValidateProgress(ctx *ExecutionContext, pageState *browser.P
 	}
 
 	remainingStepsDesc := ""
-	for i := ctx.CurrentStepNum; i < len(ctx.Plan.Steps) && i < ctx.CurrentStepNum+5; i++ {
-		remainingStepsDesc += fmt.Sprintf("%d. %s\n", i+1, ctx.Plan.Steps[i].Description)
+	for i := execCtx.CurrentStepNum; i < len(execCtx.Plan.Steps) && i < execCtx.CurrentStepNum+5; i++ {
+		remainingStepsDesc += fmt.Sprintf("%d. %s\n", i+1, execCtx.Plan.Steps[i].Description)
 	}
 
 	contentPreview := pageState.Content
@@ -49,14 +50,14 @@ func (v *Validator) ValidateProgress(ctx *ExecutionContext, pageState *browser.P
 	}
 
 	memoryInfo := ""
-	if ctx.Memory != nil {
+	if execCtx.Memory != nil {
 		memoryInfo = fmt.Sprintf(`
 Agent Memory:
 - Products viewed: %d
 - Selected product: %s
 - Items in cart: %d
 - User authenticated: %v
-`, len(ctx.Memory.ProductURLs), ctx.Memory.SelectedProduct, len(ctx.Memory.CartItems), ctx.Memory.UserCredentials["email"] != "")
+`, len(execCtx.Memory.ProductURLs), execCtx.Memory.SelectedProduct, execCtx.Memory.CartCount(), execCtx.Memory.UserCredentials["email"] != "")
 	}
 
 	prompt := fmt.Sprintf(`You are validating complex browser automation progress for an e-commerce checkout flow.
@@ -94,10 +95,13 @@ Return ONLY valid JSON:
   "message": "detailed explanation",
   "confidence": 0.0-1.0,
   "current_phase": "search|product_selection|cart|checkout|login|address|payment|complete"
-}`, ctx.TaskDescription, len(ctx.ExecutedSteps), successCount, executedStepsDesc, memoryInfo, remainingStepsDesc, pageState.URL, pageState.Title, contentPreview)
+}`, execCtx.TaskDescription, len(execCtx.ExecutedSteps), successCount, executedStepsDesc, memoryInfo, remainingStepsDesc, pageState.URL, pageState.Title, contentPreview)
 
-	response, err := v.llm.Generate(prompt)
+	response, err := v.llm.Generate(ctx, prompt)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return &ValidationResult{
 			IsComplete:      false,
 			NeedsReplanning: false,
@@ -129,9 +133,5 @@ Return ONLY valid JSON:
 		}, nil
 	}
 
-	if result.CurrentPhase != "" {
-		fmt.Printf("   📍 Current phase: %s\n", result.CurrentPhase)
-	}
-
 	return &result, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,81 @@
+package amazon_agent
+
+import (
+	"context"
+	"testing"
+
+	"browser-agent/internal/browser"
+)
+
+func TestValidateProgress(t *testing.T) {
+	execCtx := &ExecutionContext{
+		TaskDescription: "Buy detergent and go to the payment screen",
+		Plan: &Plan{Steps: []Step{
+			{Action: "navigate", Description: "Open Amazon"},
+			{Action: "proceed_checkout", Description: "Proceed to checkout"},
+		}},
+		ExecutedSteps: []ExecutedStep{
+			{Step: Step{Action: "navigate", Description: "Open Amazon"}, Success: true},
+		},
+		CurrentStepNum: 1,
+		Memory:         &AgentMemory{},
+	}
+	page := &browser.PageState{URL: "https://www.amazon.in/checkout/payment", Title: "Select a payment method"}
+
+	tests := []struct {
+		fixture string
+		want    ValidationResult
+	}{
+		{"validation_complete", ValidationResult{
+			IsComplete:   true,
+			Message:      "Reached the payment page",
+			Confidence:   0.95,
+			CurrentPhase: "payment",
+		}},
+		{"validation_replan", ValidationResult{
+			NeedsReplanning: true,
+			Message:         "The search returned no results",
+			Confidence:      0.9,
+			CurrentPhase:    "search",
+		}},
+		// A response that isn't JSON lets the run continue.
+		{"validation_malformed", ValidationResult{
+			Message:      "Continuing with plan",
+			Confidence:   0.5,
+			CurrentPhase: "unknown",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.fixture, func(t *testing.T) {
+			v := NewValidator(scriptedLLM(t, tc.fixture))
+			got, err := v.ValidateProgress(context.Background(), execCtx, page)
+			if err != nil {
+				t.Fatalf("ValidateProgress: %v", err)
+			}
+			if *got != tc.want {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateProgressWithoutLLM(t *testing.T) {
+	// An empty sequence fixture fails every call, like an unreachable
+	// provider: validation is skipped rather than failing the run.
+	v := NewValidator(scriptedLLM(t, "validation_none"))
+	execCtx := &ExecutionContext{Plan: &Plan{}, Memory: &AgentMemory{}}
+
+	got, err := v.ValidateProgress(context.Background(), execCtx, &browser.PageState{})
+	if err != nil {
+		t.Fatalf("ValidateProgress: %v", err)
+	}
+	if got.IsComplete || got.NeedsReplanning || got.CurrentPhase != "unknown" {
+		t.Errorf("got %+v, want a neutral result", *got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := v.ValidateProgress(ctx, execCtx, &browser.PageState{}); err == nil {
+		t.Error("want the context's error once it is cancelled")
+	}
+}
@@ -0,0 +1,438 @@
+package amazon_agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProductVariant is the variation of a product chosen by select_variant.
+type ProductVariant struct {
+	ASIN string `json:"asin"`
+	// Options are the selected value of each variation dimension, by the
+	// dimension's name on the page ("Colour": "Black").
+	Options map[string]string `json:"options"`
+	Price   float64           `json:"price,omitempty"`
+}
+
+func (v *ProductVariant) String() string {
+	names := make([]string, 0, len(v.Options))
+	for name := range v.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + " " + v.Options[name]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// productVariations is the variation picker ("twister") of a product page.
+type productVariations struct {
+	ASIN       string               `json:"asin"`
+	Price      float64              `json:"price"`
+	Dimensions []variationDimension `json:"dimensions"`
+}
+
+type variationDimension struct {
+	Name     string            `json:"name"`
+	Selected string            `json:"selected"`
+	Options  []variationOption `json:"options"`
+}
+
+// variationOption is one value of a dimension and the page of the variant
+// it leads to. Price is zero when the page shows none, as it doesn't for
+// unavailable options.
+type variationOption struct {
+	Value     string  `json:"value"`
+	ASIN      string  `json:"asin"`
+	URL       string  `json:"url"`
+	Price     float64 `json:"price"`
+	Available bool    `json:"available"`
+	Selected  bool    `json:"selected"`
+}
+
+// variationsScript reads the twister of a product page: swatch rows
+// ("Colour") and dropdowns ("Size"), in both the classic and the inline
+// layout.
+const variationsScript = `
+() => {
+    const number = (text) => {
+        const m = (text || '').replace(/,/g, '').match(/\d+(\.\d+)?/);
+        return m ? parseFloat(m[0]) : 0;
+    };
+    const clean = (text) => (text || '').replace(/\s+/g, ' ').trim();
+    const dpURL = (asin) => new URL('/dp/' + asin + '?th=1&psc=1', location.href).href;
+    const asinOf = (href) => {
+        const m = (href || '').match(/\/(?:dp|gp\/product)\/([A-Z0-9]{10})/);
+        return m ? m[1] : '';
+    };
+
+    const asinInput = document.querySelector('#ASIN, input[name="ASIN"]');
+    const dp = document.querySelector('#dp[data-asin]');
+    const price = document.querySelector('#corePrice_feature_div .a-price:not(.a-text-price) .a-offscreen, #corePriceDisplay_desktop_feature_div .a-price:not(.a-text-price) .a-offscreen, #priceblock_ourprice, #priceblock_dealprice');
+
+    const dimensions = [];
+    const rows = document.querySelectorAll('#twister [id^="variation_"], [id^="inline-twister-row-"]');
+    for (const row of rows) {
+        const label = row.querySelector('.a-form-label, [id^="inline-twister-dim-title-"] .a-text-bold');
+        let name = clean(label ? label.textContent : '').replace(/:$/, '');
+        if (!name) {
+            name = row.id.replace(/^variation_|^inline-twister-row-/, '').replace(/_name$/, '').replace(/_/g, ' ');
+        }
+        const selection = row.querySelector('.selection, [id^="inline-twister-expanded-dimension-text-"]');
+        const options = [];
+
+        for (const li of row.querySelectorAll('li[data-defaultasin], li[data-asin], li[data-csa-c-item-id]')) {
+            const asin = li.getAttribute('data-defaultasin') || li.getAttribute('data-asin') || asinOf(li.getAttribute('data-dp-url'));
+            const img = li.querySelector('img[alt]');
+            const value = clean((li.getAttribute('title') || '').replace(/^Click to select /, '')) ||
+                (img ? clean(img.getAttribute('alt')) : '') ||
+                clean(li.querySelector('.a-size-base, .swatch-title-text') ? li.querySelector('.a-size-base, .swatch-title-text').textContent : li.textContent);
+            const dpUrl = li.getAttribute('data-dp-url');
+            const cls = li.className || '';
+            options.push({
+                value: value,
+                asin: asin,
+                url: dpUrl ? new URL(dpUrl, location.href).href : (asin ? dpURL(asin) : ''),
+                price: number((li.querySelector('.twisterSwatchPrice, .a-price .a-offscreen') || {}).textContent),
+                available: !/unavailable|swatch-disabled/i.test(cls),
+                selected: /swatchSelect|a-button-selected|selected/.test(cls)
+            });
+        }
+
+        if (options.length === 0) {
+            for (const opt of row.querySelectorAll('select option')) {
+                if (opt.value === '-1') continue;
+                const asin = opt.value.split(',')[1] || '';
+                const [value, optPrice] = clean(opt.textContent).split(' - ');
+                options.push({
+                    value: clean(opt.getAttribute('data-a-html-content')) || value,
+                    asin: asin,
+                    url: asin ? dpURL(asin) : '',
+                    price: number(optPrice),
+                    available: !/Unavailable/.test(opt.className),
+                    selected: opt.selected
+                });
+            }
+        }
+
+        if (options.length === 0) continue;
+        const selected = options.find(o => o.selected);
+        dimensions.push({
+            name: name,
+            selected: clean(selection ? selection.textContent : '') || (selected ? selected.value : ''),
+            options: options
+        });
+    }
+
+    return {
+        asin: (asinInput && asinInput.value) || (dp && dp.getAttribute('data-asin')) || asinOf(location.href),
+        price: price ? number(price.textContent) : 0,
+        dimensions: dimensions
+    };
+}
+`
+
+// extractVariations reads the variation picker of the current product
+// page.
+func (e *Executor) extractVariations(ctx context.Context) (*productVariations, error) {
+	raw, err := e.browser.Evaluate(ctx, variationsScript)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variations: %w", err)
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variations: %w", err)
+	}
+	var v productVariations
+	if err := json.Unmarshal(encoded, &v); err != nil {
+		return nil, fmt.Errorf("failed to read variations: %w", err)
+	}
+	return &v, nil
+}
+
+// dimension finds the dimension called name, allowing for "color" and
+// "colour" and for partial names like "size" for "Size Name".
+func (v *productVariations) dimension(name string) (int, bool) {
+	want := dimensionKey(name)
+	for i, d := range v.Dimensions {
+		if dimensionKey(d.Name) == want {
+			return i, true
+		}
+	}
+	for i, d := range v.Dimensions {
+		if key := dimensionKey(d.Name); strings.Contains(key, want) || strings.Contains(want, key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func dimensionKey(name string) string {
+	key := strings.ToLower(strings.TrimSpace(name))
+	key = strings.ReplaceAll(key, "colour", "color")
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(key)
+}
+
+// option finds the option of d that value names: an exact match, else the
+// only option containing it as a word ("black" for "Matte Black").
+func (d variationDimension) option(value string) (variationOption, error) {
+	for _, o := range d.Options {
+		if strings.EqualFold(o.Value, value) {
+			return o, nil
+		}
+	}
+	word := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(value) + `\b`)
+	var found []variationOption
+	for _, o := range d.Options {
+		if word.MatchString(o.Value) {
+			found = append(found, o)
+		}
+	}
+	switch len(found) {
+	case 1:
+		return found[0], nil
+	case 0:
+		return variationOption{}, fmt.Errorf("no %s %q (options: %s)", d.Name, value, d.values(false))
+	default:
+		values := make([]string, len(found))
+		for i, o := range found {
+			values[i] = o.Value
+		}
+		return variationOption{}, fmt.Errorf("%s %q is ambiguous: %s", d.Name, value, strings.Join(values, ", "))
+	}
+}
+
+// values lists the option values of d, only the available ones if
+// available is set.
+func (d variationDimension) values(available bool) string {
+	var values []string
+	for _, o := range d.Options {
+		if o.Available || !available {
+			values = append(values, o.Value)
+		}
+	}
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+// variantChoice is one dimension's value asked for by a select_variant step.
+// Dimension is empty when the step gave only the value.
+type variantChoice struct {
+	Dimension string
+	Value     string
+}
+
+// parseVariantChoices reads a select_variant value like "colour: black,
+// size: L" (or "colour=black; size=L", or bare values like "black, L").
+func parseVariantChoices(s string) []variantChoice {
+	var choices []variantChoice
+	for _, part := range regexp.MustCompile(`[,;]`).Split(s, -1) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, ":")
+		if !ok {
+			name, value, ok = strings.Cut(part, "=")
+		}
+		if !ok {
+			name, value = "", part
+		}
+		choices = append(choices, variantChoice{Dimension: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	return choices
+}
+
+// variantChoicesFromTask finds the option values of v mentioned in a task
+// like "buy the white polo in size M". Values of one or two letters, like
+// sizes, count only right after their dimension's name.
+func variantChoicesFromTask(task string, v *productVariations) []variantChoice {
+	var choices []variantChoice
+	for _, d := range v.Dimensions {
+		options := append([]variationOption(nil), d.Options...)
+		// "Matte Black" before "Black".
+		sort.SliceStable(options, func(i, j int) bool { return len(options[i].Value) > len(options[j].Value) })
+		for _, o := range options {
+			pattern := `(?i)\b` + regexp.QuoteMeta(o.Value) + `\b`
+			if len(o.Value) <= 2 {
+				name := regexp.QuoteMeta(d.Name)
+				if strings.EqualFold(d.Name, "colour") || strings.EqualFold(d.Name, "color") {
+					name = "colou?r"
+				}
+				pattern = `(?i)\b` + name + `\s*[:=]?\s*` + regexp.QuoteMeta(o.Value) + `\b`
+			}
+			if regexp.MustCompile(pattern).MatchString(task) {
+				choices = append(choices, variantChoice{Dimension: d.Name, Value: o.Value})
+				break
+			}
+		}
+	}
+	return choices
+}
+
+// executeSelectVariant picks the product variation the step's value (or
+// else the task) asks for, one dimension at a time, by opening the page of
+// the variant each option leads to.
+func (e *Executor) executeSelectVariant(ctx context.Context, step Step, execCtx *ExecutionContext) (*ExecutionResult, error) {
+	variations, err := e.extractVariations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(variations.Dimensions) == 0 {
+		return nil, fmt.Errorf("no variations on this page")
+	}
+	e.logVariations(variations)
+
+	choices := parseVariantChoices(step.GetValueString())
+	if len(choices) == 0 && execCtx != nil {
+		choices = variantChoicesFromTask(execCtx.TaskDescription, variations)
+		if len(choices) > 0 {
+			e.logf("   🧾 From the task: %s\n", describeChoices(choices))
+		}
+	}
+	if len(choices) == 0 {
+		return nil, fmt.Errorf("no variant asked for: give the step a value like \"colour: black, size: L\"")
+	}
+
+	basePrice := variations.Price
+	for _, choice := range choices {
+		var dim variationDimension
+		if choice.Dimension != "" {
+			i, ok := variations.dimension(choice.Dimension)
+			if !ok {
+				return nil, fmt.Errorf("product has no %s variation (has: %s)", choice.Dimension, dimensionNames(variations))
+			}
+			dim = variations.Dimensions[i]
+		} else {
+			found := false
+			for _, d := range variations.Dimensions {
+				if _, err := d.option(choice.Value); err == nil {
+					dim, found = d, true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("no variation %q (has: %s)", choice.Value, dimensionNames(variations))
+			}
+		}
+
+		option, err := dim.option(choice.Value)
+		if err != nil {
+			return nil, err
+		}
+		if option.Selected {
+			e.logf("   ✓ %s %s already selected\n", dim.Name, option.Value)
+			continue
+		}
+		if !option.Available {
+			return nil, fmt.Errorf("%s %s is unavailable (available: %s)", dim.Name, option.Value, dim.values(true))
+		}
+		if option.URL == "" {
+			return nil, fmt.Errorf("%s %s has no page to open", dim.Name, option.Value)
+		}
+
+		e.logf("   🎨 Selecting %s: %s\n", dim.Name, option.Value)
+		if err := e.browser.Navigate(ctx, option.URL); err != nil {
+			return nil, fmt.Errorf("failed to open %s %s: %w", dim.Name, option.Value, err)
+		}
+		e.browser.WaitForSelector(ctx, "#twister, [id^='inline-twister-row-']", 10*time.Second)
+
+		if variations, err = e.extractVariations(ctx); err != nil {
+			return nil, err
+		}
+		i, ok := variations.dimension(dim.Name)
+		if !ok || !strings.EqualFold(variations.Dimensions[i].Selected, option.Value) {
+			return nil, fmt.Errorf("%s %s did not get selected", dim.Name, option.Value)
+		}
+	}
+
+	variant := &ProductVariant{
+		ASIN:    variations.ASIN,
+		Options: make(map[string]string),
+		Price:   variations.Price,
+	}
+	for _, d := range variations.Dimensions {
+		variant.Options[d.Name] = d.Selected
+	}
+
+	price := ""
+	if variant.Price > 0 {
+		price = " (" + formatRupees(variant.Price)
+		if delta := priceDelta(basePrice, variant.Price); delta != "" {
+			price += ", " + delta
+		}
+		price += ")"
+	}
+	e.logf("   ✓ Selected variant: %s%s\n", variant, price)
+
+	pageState := e.currentPage(ctx)
+	return &ExecutionResult{
+		Success: true,
+		Message: fmt.Sprintf("Selected variant %s%s", variant, price),
+		Data: map[string]interface{}{
+			"variant":     variant,
+			"product_url": pageState.URL,
+			"asin":        variant.ASIN,
+			"price":       variant.Price,
+		},
+	}, nil
+}
+
+// logVariations lists every option of the page with its availability and
+// its price against the selected variant's.
+func (e *Executor) logVariations(v *productVariations) {
+	for _, d := range v.Dimensions {
+		options := make([]string, len(d.Options))
+		for i, o := range d.Options {
+			switch {
+			case o.Selected:
+				options[i] = o.Value + " (selected)"
+			case !o.Available:
+				options[i] = o.Value + " (unavailable)"
+			default:
+				options[i] = o.Value
+				if delta := priceDelta(v.Price, o.Price); delta != "" {
+					options[i] += " (" + delta + ")"
+				}
+			}
+		}
+		e.logf("   🎨 %s: %s\n", d.Name, strings.Join(options, ", "))
+	}
+}
+
+// priceDelta is price against base, like "+₹50", or "" when either is
+// unknown or they are the same.
+func priceDelta(base, price float64) string {
+	if base == 0 || price == 0 || base == price {
+		return ""
+	}
+	if price > base {
+		return "+" + formatRupees(price-base)
+	}
+	return "-" + formatRupees(base-price)
+}
+
+func describeChoices(choices []variantChoice) string {
+	parts := make([]string, len(choices))
+	for i, c := range choices {
+		parts[i] = c.Dimension + ": " + c.Value
+	}
+	return strings.Join(parts, ", ")
+}
+
+func dimensionNames(v *productVariations) string {
+	names := make([]string, len(v.Dimensions))
+	for i, d := range v.Dimensions {
+		names[i] = d.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -1,7 +1,11 @@
 package browser
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/playwright-community/playwright-go"
@@ -12,23 +16,48 @@ type Browser struct {
 	browser playwright.Browser
 	context playwright.BrowserContext
 	page    playwright.Page
+
+	opts Options
+	// videoTmp holds raw video recordings until Close moves the final one
+	// to the output directory.
+	videoTmp  string
+	outputDir string
+}
+
+type Options struct {
+	Headless bool
+	SlowMo   float64
+	// StorageStatePath seeds the first context with the cookies and
+	// localStorage saved there.
+	StorageStatePath string
+	// Trace records a Playwright trace (actions, DOM snapshots, network,
+	// screenshots and sources), saved as trace.zip on Close.
+	Trace bool
+	// Video records the page, saved as video.webm on Close.
+	Video bool
 }
 
+// Recording file names written to the output directory by Close.
+const (
+	TraceFile = "trace.zip"
+	VideoFile = "video.webm"
+)
+
 type PageState struct {
 	URL     string
 	Title   string
 	Content string
 }
 
-func NewBrowser(headless bool, slowMo float64) (*Browser, error) {
+func NewBrowser(opts Options) (*Browser, error) {
 	pw, err := playwright.Run()
 	if err != nil {
 		return nil, fmt.Errorf("start playwright: %w", err)
 	}
 
 	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(headless),
-		SlowMo:   playwright.Float(slowMo),
+		Headless: playwright.Bool(opts.Headless),
+		SlowMo:   playwright.Float(opts.SlowMo),
 		Args: []string{
 			"--disable-blink-features=AutomationControlled",
 		},
@@ -38,19 +67,67 @@ func NewBrowser(headless bool, slowMo float64) (*Browser, error) {
 		return nil, fmt.Errorf("launch browser: %w", err)
 	}
 
-	context, err := browser.NewContext()
-	if err != nil {
+	b := &Browser{
+		pw:      pw,
+		browser: browser,
+		opts:    opts,
+	}
+	if opts.Video {
+		b.videoTmp, err = os.MkdirTemp("", "browser-agent-video-")
+		if err != nil {
+			browser.Close()
+			pw.Stop()
+			return nil, fmt.Errorf("create video directory: %w", err)
+		}
+	}
+	if b.context, b.page, err = b.newContext(opts.StorageStatePath); err != nil {
 		browser.Close()
 		pw.Stop()
-		return nil, fmt.Errorf("create context: %w", err)
+		b.removeVideoTmp()
+		return nil, err
+	}
+	return b, nil
+}
+
+// SetOutputDir sets where Close saves the trace and video, typically the
+// run directory. Without one, recordings are discarded.
+func (b *Browser) SetOutputDir(dir string) {
+	b.outputDir = dir
+}
+
+// newContext creates a fresh browser context and page, optionally seeded
+// with cookies and localStorage from a Playwright storage state file. The
+// caller installs them as b.context and b.page.
+func (b *Browser) newContext(storageStatePath string) (playwright.BrowserContext, playwright.Page, error) {
+	opts := playwright.BrowserNewContextOptions{}
+	if storageStatePath != "" {
+		opts.StorageStatePath = playwright.String(storageStatePath)
+	}
+	if b.videoTmp != "" {
+		opts.RecordVideo = &playwright.RecordVideo{Dir: b.videoTmp}
+	}
+
+	context, err := b.browser.NewContext(opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create context: %w", err)
+	}
+
+	if b.opts.Trace {
+		err := context.Tracing().Start(playwright.TracingStartOptions{
+			Screenshots: playwright.Bool(true),
+			Snapshots:   playwright.Bool(true),
+			Sources:     playwright.Bool(true),
+		})
+		if err != nil {
+			context.Close()
+			return nil, nil, fmt.Errorf("start tracing: %w", err)
+		}
 	}
 
 	page, err := context.NewPage()
 	if err != nil {
 		context.Close()
-		browser.Close()
-		pw.Stop()
-		return nil, fmt.Errorf("create page: %w", err)
+		return nil, nil, fmt.Errorf("create page: %w", err)
 	}
 
 	// Add stealth scripts
@@ -62,109 +139,304 @@ func NewBrowser(headless bool, slowMo float64) (*Browser, error) {
 		`),
 	})
 
-	return &Browser{
-		pw:      pw,
-		browser: browser,
-		context: context,
-		page:    page,
-	}, nil
+	return context, page, nil
 }
 
-func (b *Browser) Navigate(url string) error {
-	_, err := b.page.Goto(url, playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateLoad,
-		Timeout:   playwright.Float(60000),
-	})
-	if err != nil {
-		return err
+// do runs a Playwright call and returns early with ctx.Err() when ctx is
+// cancelled. Playwright has no cancellation of its own, so the abandoned
+// call finishes in the background against its own timeout.
+func do[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
 	}
-	time.Sleep(2 * time.Second)
-	return nil
-}
-
-func (b *Browser) Click(selector string) error {
-	return b.page.Click(selector, playwright.PageClickOptions{
-		Timeout: playwright.Float(10000),
-	})
-}
 
-func (b *Browser) Type(selector string, text string) error {
-	return b.page.Fill(selector, text)
-}
+	type result struct {
+		val T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		val, err := fn()
+		done <- result{val, err}
+	}()
 
-func (b *Browser) Press(selector string, key string) error {
-	return b.page.Press(selector, key)
+	select {
+	case r := <-done:
+		return r.val, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
 }
 
-func (b *Browser) WaitForSelector(selector string, timeout time.Duration) error {
-	_, err := b.page.WaitForSelector(selector, playwright.PageWaitForSelectorOptions{
-		Timeout: playwright.Float(float64(timeout.Milliseconds())),
+func doErr(ctx context.Context, fn func() error) error {
+	_, err := do(ctx, func() (struct{}, error) {
+		return struct{}{}, fn()
 	})
 	return err
 }
 
-func (b *Browser) GetText(selector string) (string, error) {
-	element, err := b.page.QuerySelector(selector)
-	if err != nil {
-		return "", err
+// Sleep pauses for d or until ctx is cancelled, whichever comes first.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	if element == nil {
-		return "", fmt.Errorf("element not found")
-	}
-	text, err := element.TextContent()
+}
+
+func (b *Browser) Navigate(ctx context.Context, url string) error {
+	err := doErr(ctx, func() error {
+		_, err := b.page.Goto(url, playwright.PageGotoOptions{
+			WaitUntil: playwright.WaitUntilStateLoad,
+			Timeout:   playwright.Float(60000),
+		})
+		return err
+	})
 	if err != nil {
-		return "", err
+		return err
 	}
-	return text, nil
+	return Sleep(ctx, 2*time.Second)
 }
 
-func (b *Browser) GetPageState() (*PageState, error) {
-	url := b.page.URL()
-	title, err := b.page.Title()
+// Reload reloads the current page.
+func (b *Browser) Reload(ctx context.Context) error {
+	err := doErr(ctx, func() error {
+		_, err := b.page.Reload(playwright.PageReloadOptions{
+			WaitUntil: playwright.WaitUntilStateLoad,
+			Timeout:   playwright.Float(60000),
+		})
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return Sleep(ctx, 2*time.Second)
+}
 
-	body, err := b.page.QuerySelector("body")
+// GoBack navigates to the previous page in the history. It is an error if
+// there is none.
+func (b *Browser) GoBack(ctx context.Context) error {
+	before := b.page.URL()
+	err := doErr(ctx, func() error {
+		_, err := b.page.GoBack(playwright.PageGoBackOptions{
+			WaitUntil: playwright.WaitUntilStateLoad,
+			Timeout:   playwright.Float(60000),
+		})
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return err
 	}
+	if b.page.URL() == before {
+		return fmt.Errorf("no previous page to go back to")
+	}
+	return Sleep(ctx, 2*time.Second)
+}
+
+func (b *Browser) Click(ctx context.Context, selector string) error {
+	return doErr(ctx, func() error {
+		return b.page.Click(selector, playwright.PageClickOptions{
+			Timeout: playwright.Float(10000),
+		})
+	})
+}
 
-	var content string
-	if body != nil {
-		content, err = body.TextContent()
+func (b *Browser) Type(ctx context.Context, selector string, text string) error {
+	return doErr(ctx, func() error {
+		return b.page.Fill(selector, text)
+	})
+}
+
+func (b *Browser) Press(ctx context.Context, selector string, key string) error {
+	return doErr(ctx, func() error {
+		return b.page.Press(selector, key)
+	})
+}
+
+func (b *Browser) WaitForSelector(ctx context.Context, selector string, timeout time.Duration) error {
+	return doErr(ctx, func() error {
+		_, err := b.page.WaitForSelector(selector, playwright.PageWaitForSelectorOptions{
+			Timeout: playwright.Float(float64(timeout.Milliseconds())),
+		})
+		return err
+	})
+}
+
+func (b *Browser) GetText(ctx context.Context, selector string) (string, error) {
+	return do(ctx, func() (string, error) {
+		element, err := b.page.QuerySelector(selector)
 		if err != nil {
-			content = ""
+			return "", err
 		}
-	}
+		if element == nil {
+			return "", fmt.Errorf("element not found")
+		}
+		text, err := element.TextContent()
+		if err != nil {
+			return "", err
+		}
+		return text, nil
+	})
+}
 
-	return &PageState{
-		URL:     url,
-		Title:   title,
-		Content: content,
-	}, nil
+func (b *Browser) GetPageState(ctx context.Context) (*PageState, error) {
+	return do(ctx, func() (*PageState, error) {
+		url := b.page.URL()
+		title, err := b.page.Title()
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := b.page.QuerySelector("body")
+		if err != nil {
+			return nil, err
+		}
+
+		var content string
+		if body != nil {
+			content, err = body.TextContent()
+			if err != nil {
+				content = ""
+			}
+		}
+
+		return &PageState{
+			URL:     url,
+			Title:   title,
+			Content: content,
+		}, nil
+	})
 }
 
-func (b *Browser) Screenshot() ([]byte, error) {
-	return b.page.Screenshot()
+func (b *Browser) Screenshot(ctx context.Context) ([]byte, error) {
+	return do(ctx, func() ([]byte, error) {
+		return b.page.Screenshot()
+	})
 }
 
-func (b *Browser) Evaluate(script string) (interface{}, error) {
-	return b.page.Evaluate(script)
+// HTML returns the current page's serialized DOM.
+func (b *Browser) HTML(ctx context.Context) (string, error) {
+	return do(ctx, func() (string, error) {
+		return b.page.Content()
+	})
 }
 
+func (b *Browser) Evaluate(ctx context.Context, script string) (interface{}, error) {
+	return do(ctx, func() (interface{}, error) {
+		return b.page.Evaluate(script)
+	})
+}
+
+// URL returns the current page URL without touching the page.
+func (b *Browser) URL() string {
+	return b.page.URL()
+}
+
+// SaveStorageState writes the context's cookies and localStorage to path.
+// The file holds live session cookies, so it is only readable by the owner.
+func (b *Browser) SaveStorageState(ctx context.Context, path string) error {
+	return doErr(ctx, func() error {
+		if _, err := b.context.StorageState(path); err != nil {
+			return err
+		}
+		return os.Chmod(path, 0o600)
+	})
+}
+
+// LoadStorageState replaces the current context with one restored from a
+// storage state file written by SaveStorageState. Open pages are closed.
+func (b *Browser) LoadStorageState(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("storage state: %w", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Opening is left to finish in the background when ctx is cancelled,
+	// as do does, but b is only changed here, once it has succeeded.
+	type opened struct {
+		context playwright.BrowserContext
+		page    playwright.Page
+		err     error
+	}
+	done := make(chan opened, 1)
+	go func() {
+		context, page, err := b.newContext(path)
+		done <- opened{context, page, err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err != nil {
+			return o.err
+		}
+		oldContext := b.context
+		b.context, b.page = o.context, o.page
+		if oldContext != nil {
+			oldContext.Close()
+		}
+		return nil
+	case <-ctx.Done():
+		go func() {
+			if o := <-done; o.err == nil {
+				o.context.Close()
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
+// Close saves the trace and video to the output directory, if set, and
+// shuts the browser down. Recording errors are returned after everything
+// has been closed.
 func (b *Browser) Close() error {
+	var errs []error
+
+	if b.context != nil && b.opts.Trace {
+		var path []string
+		if b.outputDir != "" {
+			path = append(path, filepath.Join(b.outputDir, TraceFile))
+		}
+		if err := b.context.Tracing().Stop(path...); err != nil {
+			errs = append(errs, fmt.Errorf("save trace: %w", err))
+		}
+	}
+
+	var video playwright.Video
 	if b.page != nil {
+		video = b.page.Video()
 		b.page.Close()
 	}
+	if video != nil && b.outputDir != "" {
+		// SaveAs waits for the closed page's recording to be finalised.
+		if err := video.SaveAs(filepath.Join(b.outputDir, VideoFile)); err != nil {
+			errs = append(errs, fmt.Errorf("save video: %w", err))
+		}
+	}
+
 	if b.context != nil {
 		b.context.Close()
 	}
 	if b.browser != nil {
 		b.browser.Close()
 	}
+	b.removeVideoTmp()
 	if b.pw != nil {
-		return b.pw.Stop()
+		if err := b.pw.Stop(); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return nil
-}
\ No newline at end of file
+	return errors.Join(errs...)
+}
+
+func (b *Browser) removeVideoTmp() {
+	if b.videoTmp != "" {
+		os.RemoveAll(b.videoTmp)
+	}
+}
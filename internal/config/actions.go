@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Per-action overrides are addressed as actions.<action>.<property>, e.g.
+// actions.navigate.step_timeout. In a config file they nest under an
+// "actions" table; in the environment they are AGENT_ACTIONS_<ACTION>_<PROPERTY>;
+// on the command line they are given with the repeatable --action flag as
+// <action>.<property>=<value>.
+const actionsKey = "actions"
+
+var actionProperties = []string{"step_timeout", "max_retries", "retry_delay"}
+
+// setAction applies one actions.<action>.<property> key.
+func (c *Config) setAction(key, value, source string) error {
+	rest := strings.TrimPrefix(key, actionsKey+".")
+	name, prop, ok := strings.Cut(rest, ".")
+	if !ok || name == "" {
+		return fmt.Errorf("config key %q: want actions.<action>.<property>", key)
+	}
+	name = strings.ToLower(name)
+
+	if c.Actions == nil {
+		c.Actions = make(map[string]ActionConfig)
+	}
+	ac := c.Actions[name]
+	value = strings.TrimSpace(value)
+	switch prop {
+	case "step_timeout", "retry_delay":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid duration %q (e.g. 45s, 2m)", key, value)
+		}
+		if prop == "step_timeout" {
+			ac.StepTimeout = &d
+		} else {
+			ac.RetryDelay = &d
+		}
+	case "max_retries":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s: invalid retry count %q", key, value)
+		}
+		ac.MaxRetries = &n
+	default:
+		return fmt.Errorf("config key %q: unknown action property %q (want %s)", key, prop, strings.Join(actionProperties, ", "))
+	}
+	c.Actions[name] = ac
+
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[actionsKey+"."+name+"."+prop] = source
+	return nil
+}
+
+// loadActionsTable applies the "actions" table of a config file.
+func (c *Config) loadActionsTable(path string, value interface{}) error {
+	actions, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("config file %s: %s must be a table of actions", path, actionsKey)
+	}
+	for _, name := range sortedKeys(actions) {
+		props, ok := actions[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("config file %s: %s.%s must be a table", path, actionsKey, name)
+		}
+		for _, prop := range sortedKeys(props) {
+			key := actionsKey + "." + name + "." + prop
+			if err := c.setAction(key, fmt.Sprint(props[prop]), "file "+path); err != nil {
+				return fmt.Errorf("config file %s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadActionEnv applies AGENT_ACTIONS_<ACTION>_<PROPERTY> variables.
+func (c *Config) loadActionEnv(environ []string) error {
+	prefix := EnvPrefix + strings.ToUpper(actionsKey) + "_"
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		matched := false
+		for _, prop := range actionProperties {
+			if action, ok := strings.CutSuffix(rest, "_"+strings.ToUpper(prop)); ok && action != "" {
+				if err := c.setAction(actionsKey+"."+action+"."+prop, value, "env "+name); err != nil {
+					return err
+				}
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: want %s<ACTION>_<STEP_TIMEOUT|MAX_RETRIES|RETRY_DELAY>", name, prefix)
+		}
+	}
+	return nil
+}
+
+// actionFields lists the per-action overrides that are set, by action name.
+func (c *Config) actionFields() []Field {
+	var out []Field
+	for _, name := range sortedKeys(c.Actions) {
+		ac := c.Actions[name]
+		values := map[string]string{}
+		if ac.StepTimeout != nil {
+			values["step_timeout"] = ac.StepTimeout.String()
+		}
+		if ac.MaxRetries != nil {
+			values["max_retries"] = strconv.Itoa(*ac.MaxRetries)
+		}
+		if ac.RetryDelay != nil {
+			values["retry_delay"] = ac.RetryDelay.String()
+		}
+		for _, prop := range actionProperties {
+			value, ok := values[prop]
+			if !ok {
+				continue
+			}
+			key := actionsKey + "." + name + "." + prop
+			out = append(out, Field{
+				Key:    key,
+				Value:  value,
+				Source: c.Source(key),
+				Env:    EnvPrefix + strings.ToUpper(actionsKey+"_"+name+"_"+prop),
+				Flag:   "--action " + name + "." + prop,
+			})
+		}
+	}
+	return out
+}
+
+// actionFlag is the repeatable --action <action>.<property>=<value> flag.
+type actionFlag struct {
+	c *Config
+}
+
+func (f *actionFlag) String() string { return "" }
+
+func (f *actionFlag) Set(value string) error {
+	key, v, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("want <action>.<property>=<value>, e.g. navigate.step_timeout=60s")
+	}
+	return f.c.setAction(actionsKey+"."+strings.TrimSpace(key), v, "flag --action")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,26 +2,148 @@ package config
 
 import "time"
 
+// Config is the agent configuration. NewConfig holds the defaults, which
+// Load layers a config file and AGENT_* environment variables over, and
+// RegisterFlags lets command-line flags override last. The config tag is
+// the key in config files; the environment variable is AGENT_ plus the
+// upper-cased key.
 type Config struct {
-	MaxSteps      int
-	StepTimeout   time.Duration
-	TotalTimeout  time.Duration
-	Headless      bool
-	SlowMo        float64
-	MaxRetries    int
-	RetryDelay    time.Duration
-	EnableRecovery bool
+	MaxSteps     int           `config:"max_steps"`
+	TotalTimeout time.Duration `config:"total_timeout"`
+	Headless     bool          `config:"headless"`
+	SlowMo       float64       `config:"slow_mo"`
+
+	// StepTimeout bounds each attempt of a step; zero means no deadline.
+	// A failed critical step is retried up to MaxRetries times, waiting
+	// RetryDelay before the first retry and doubling (with jitter) up to
+	// MaxRetryDelay before each later one. Actions overrides these per
+	// action name.
+	StepTimeout   time.Duration `config:"step_timeout"`
+	MaxRetries    int           `config:"max_retries"`
+	RetryDelay    time.Duration `config:"retry_delay"`
+	MaxRetryDelay time.Duration `config:"max_retry_delay"`
+	Actions       map[string]ActionConfig
+
+	// EnableRecovery turns on recovery after repeated step failures, which
+	// tries RecoveryStrategies in order until one succeeds.
+	EnableRecovery     bool     `config:"enable_recovery"`
+	RecoveryStrategies []string `config:"recovery_strategies"`
+
+	// ValidationInterval is how many steps pass between the LLM's checks
+	// of the run's progress; 0 turns the checks off.
+	ValidationInterval int `config:"validation_interval"`
+
+	// SmartActionConfidence is the confidence (0-1) the LLM must report
+	// for a smart_action suggestion to be carried out.
+	SmartActionConfidence float64 `config:"smart_action_confidence"`
+
+	// SearchPages and SearchLimit bound how many search results pages
+	// select_product and collect_products read, and how many products they
+	// collect across them.
+	SearchPages int `config:"search_pages"`
+	SearchLimit int `config:"search_limit"`
+
+	// CartReconcile is what proceed_checkout does when the cart page
+	// differs from the items the task added: "fail" the step, "repair" it
+	// (removing lines the task didn't add, even ones the user had in the
+	// cart before), or "off" to not check.
+	CartReconcile string `config:"cart_reconcile"`
+
+	// OutputSchema is the JSON Schema of the answer the task must produce,
+	// inline or as the path of a JSON file. When empty the planner may
+	// infer one for tasks that ask for information.
+	OutputSchema string `config:"output_schema"`
+
+	// LLM provider selection: openrouter, openai, anthropic or ollama.
+	// Empty model and base URL use the provider defaults.
+	LLMProvider string `config:"llm_provider"`
+	LLMModel    string `config:"llm_model"`
+	LLMBaseURL  string `config:"llm_base_url"`
+
+	// LLMFixture is the recorded-response file used by the replay
+	// provider; LLMRecord captures live responses into a fixture file.
+	LLMFixture string `config:"llm_fixture" flag:"llm-replay"`
+	LLMRecord  string `config:"llm_record"`
+
+	// RunsDir holds one directory per run with its checkpoint and browser
+	// session, used by `agent resume`.
+	RunsDir string `config:"runs_dir"`
+
+	// Session names a saved browser profile (cookies and localStorage) in
+	// SessionsDir. It is loaded at start-up and saved after login and at
+	// the end of the run, so later runs skip signing in again.
+	Session     string `config:"session"`
+	SessionsDir string `config:"sessions_dir"`
+
+	// LogFormat selects how run events are written to stdout: "text" for
+	// the console renderer or "json" for one JSON event per line.
+	LogFormat string `config:"log_format"`
+
+	// Output selects what stdout carries: "text" for the progress and run
+	// summary, or "json" for only the task's structured output, with the
+	// progress moved to stderr.
+	Output string `config:"output"`
+
+	// ArtifactMode controls the screenshots and HTML snapshots written to
+	// the run directory: "steps" before and after every step, "failures"
+	// only when a step fails, or "off".
+	ArtifactMode string `config:"artifact_mode" flag:"artifacts"`
+
+	// Trace and Video record a Playwright trace (trace.zip, open with
+	// `npx playwright show-trace`) and a WebM video of the page into the
+	// run directory.
+	Trace bool `config:"trace"`
+	Video bool `config:"video"`
+
+	// sources records where each value was set, by config key; keys
+	// missing from it still hold their default.
+	sources map[string]string
 }
 
+// ActionConfig overrides the step timeout and retry policy for one action.
+// Nil fields inherit the global values; a StepTimeout of zero means no
+// deadline, as for the global setting.
+type ActionConfig struct {
+	StepTimeout *time.Duration
+	MaxRetries  *int
+	RetryDelay  *time.Duration
+}
+
+func duration(d time.Duration) *time.Duration { return &d }
+
 func NewConfig() *Config {
 	return &Config{
-		MaxSteps:      150,  // Increased for complex tasks
+		MaxSteps:      150, // Increased for complex tasks
 		StepTimeout:   45 * time.Second,
-		TotalTimeout:  20 * time.Minute,  // Increased timeout
+		TotalTimeout:  20 * time.Minute, // Increased timeout
 		Headless:      false,
 		SlowMo:        100,
 		MaxRetries:    3,
 		RetryDelay:    2 * time.Second,
-		EnableRecovery: true,
+		MaxRetryDelay: 30 * time.Second,
+		Actions: map[string]ActionConfig{
+			// Page loads alone may take the browser's full 60s goto timeout.
+			"navigate": {StepTimeout: duration(75 * time.Second)},
+			"wait":     {StepTimeout: duration(2 * time.Minute)},
+			// These wait for a human to type credentials or an address.
+			"login":        {StepTimeout: duration(10 * time.Minute)},
+			"fill_address": {StepTimeout: duration(10 * time.Minute)},
+			// Asks the LLM before acting.
+			"smart_action": {StepTimeout: duration(90 * time.Second)},
+		},
+		EnableRecovery:     true,
+		RecoveryStrategies: []string{"llm_plan"},
+		ValidationInterval: 5,
+
+		SmartActionConfidence: 0.6,
+		SearchPages:           3,
+		SearchLimit:           60,
+		CartReconcile:         "fail",
+		LLMProvider:           "openrouter",
+		RunsDir:               "runs",
+		SessionsDir:           "sessions",
+		LogFormat:             "text",
+		Output:                "text",
+		ArtifactMode:          "steps",
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,345 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variable of every config key, e.g.
+// AGENT_MAX_STEPS for max_steps.
+const EnvPrefix = "AGENT_"
+
+// DefaultFiles are looked up in the working directory when no config file
+// is named explicitly.
+var DefaultFiles = []string{"agent.yaml", "agent.yml", "agent.toml"}
+
+const sourceDefault = "default"
+
+// Field is one configuration value as shown by `agent config show`.
+type Field struct {
+	Key    string
+	Value  string
+	Source string
+	Env    string
+	Flag   string
+}
+
+type fieldInfo struct {
+	key   string
+	flag  string
+	index int
+}
+
+var fields = func() []fieldInfo {
+	var out []fieldInfo
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := f.Tag.Get("config")
+		if key == "" {
+			continue
+		}
+		name := f.Tag.Get("flag")
+		if name == "" {
+			name = strings.ReplaceAll(key, "_", "-")
+		}
+		out = append(out, fieldInfo{key: key, flag: name, index: i})
+	}
+	return out
+}()
+
+func lookupField(key string) (fieldInfo, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return fieldInfo{}, false
+}
+
+// FilePath picks the config file: --config in args, then $AGENT_CONFIG,
+// then the first of DefaultFiles that exists. It returns "" if there is
+// none.
+func FilePath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	if path := os.Getenv(EnvPrefix + "CONFIG"); path != "" {
+		return path
+	}
+	for _, name := range DefaultFiles {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// Load returns the defaults overlaid with the config file at path, if any,
+// and then with AGENT_* environment variables.
+func Load(path string) (*Config, error) {
+	cfg := NewConfig()
+	if path != "" {
+		if err := cfg.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.LoadEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFile applies a YAML (.yaml, .yml) or TOML (.toml) file of config
+// keys. Unknown keys are an error so typos don't go unnoticed.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q (use .yaml or .toml)", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+
+	for _, key := range sortedKeys(values) {
+		value := values[key]
+		if key == actionsKey {
+			if err := c.loadActionsTable(path, value); err != nil {
+				return err
+			}
+			continue
+		}
+		switch v := value.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			return fmt.Errorf("config file %s: %s must be a single value", path, key)
+		case []interface{}:
+			if f, ok := lookupField(key); !ok || !isList(f) {
+				return fmt.Errorf("config file %s: %s must be a single value", path, key)
+			}
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			value = strings.Join(items, ",")
+		}
+		if err := c.Set(key, fmt.Sprint(value), "file "+path); err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// LoadEnv applies the AGENT_<KEY> and AGENT_ACTIONS_<ACTION>_<PROPERTY>
+// variables of environ, given as "NAME=value" like os.Environ.
+func (c *Config) LoadEnv(environ []string) error {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			env[name] = value
+		}
+	}
+	for _, f := range fields {
+		name := EnvPrefix + strings.ToUpper(f.key)
+		if value, ok := env[name]; ok {
+			if err := c.Set(f.key, value, "env "+name); err != nil {
+				return err
+			}
+		}
+	}
+	return c.loadActionEnv(environ)
+}
+
+// Set parses value into the field for key and records source as its
+// origin. Durations use Go syntax ("45s", "20m") and lists are
+// comma-separated. Per-action overrides use
+// keys of the form actions.<action>.<property>.
+func (c *Config) Set(key, value, source string) error {
+	if strings.HasPrefix(key, actionsKey+".") {
+		return c.setAction(key, value, source)
+	}
+	f, ok := lookupField(key)
+	if !ok {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+
+	v := reflect.ValueOf(c).Elem().Field(f.index)
+	value = strings.TrimSpace(value)
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid duration %q (e.g. 45s, 2m)", key, value)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q", key, value)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: invalid number %q", key, value)
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid boolean %q", key, value)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		v.SetString(value)
+	}
+
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[key] = source
+	return nil
+}
+
+// Source describes where the value for key came from: "default",
+// "file <path>", "env <VAR>" or "flag --<name>".
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return sourceDefault
+}
+
+func (c *Config) value(f fieldInfo) string {
+	v := reflect.ValueOf(c).Elem().Field(f.index)
+	switch x := v.Interface().(type) {
+	case time.Duration:
+		return x.String()
+	case []string:
+		return strings.Join(x, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Fields lists every key with its effective value and source, in
+// declaration order, followed by the per-action overrides that are set.
+func (c *Config) Fields() []Field {
+	out := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, Field{
+			Key:    f.key,
+			Value:  c.value(f),
+			Source: c.Source(f.key),
+			Env:    EnvPrefix + strings.ToUpper(f.key),
+			Flag:   "--" + f.flag,
+		})
+	}
+	return append(out, c.actionFields()...)
+}
+
+// RegisterFlags defines a flag for every config key on fs. Flags set on the
+// command line override the file and environment and are recorded as the
+// value's source. The defaults shown in -help are the values loaded so far.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	for _, f := range fields {
+		fs.Var(&fieldFlag{c: c, field: f}, f.flag, flagUsage[f.key])
+	}
+	fs.Var(&actionFlag{c: c}, "action", "override a setting for one action as <action>.<property>=<value>, e.g. navigate.step_timeout=60s (repeatable)")
+}
+
+type fieldFlag struct {
+	c     *Config
+	field fieldInfo
+}
+
+func (f *fieldFlag) String() string {
+	if f == nil || f.c == nil {
+		return ""
+	}
+	return f.c.value(f.field)
+}
+
+func (f *fieldFlag) Set(value string) error {
+	return f.c.Set(f.field.key, value, "flag --"+f.field.flag)
+}
+
+func isList(f fieldInfo) bool {
+	return reflect.TypeOf(Config{}).Field(f.index).Type.Kind() == reflect.Slice
+}
+
+func (f *fieldFlag) IsBoolFlag() bool {
+	return reflect.TypeOf(Config{}).Field(f.field.index).Type.Kind() == reflect.Bool
+}
+
+var flagUsage = map[string]string{
+	"max_steps":               "maximum number of steps to execute",
+	"step_timeout":            "time limit for a single step attempt (0 for none)",
+	"total_timeout":           "time limit for the whole task",
+	"headless":                "run the browser without a window",
+	"slow_mo":                 "delay in milliseconds between browser operations",
+	"max_retries":             "retries for a failed critical step",
+	"retry_delay":             "delay before the first retry; doubles with jitter for each later one",
+	"max_retry_delay":         "upper bound for the delay between retries",
+	"enable_recovery":         "recover from repeated failures",
+	"validation_interval":     "steps between LLM progress checks (0 for none)",
+	"recovery_strategies":     "recovery strategies to try in order, comma-separated: reload_and_retry, go_back, llm_plan, restart_from_checkpoint or none",
+	"smart_action_confidence": "minimum LLM confidence (0-1) for a smart_action suggestion to be carried out",
+	"search_pages":            "most search results pages select_product and collect_products read",
+	"search_limit":            "most products select_product and collect_products collect across pages",
+	"cart_reconcile":          "when the cart differs from the items added, before checkout: fail, repair or off",
+	"output_schema":           "JSON Schema of the task's answer, inline or a .json file path",
+	"llm_provider":            "LLM provider: openrouter, openai, anthropic, ollama or replay",
+	"llm_model":               "LLM model (default: the provider's)",
+	"llm_base_url":            "LLM API base URL (default: the provider's)",
+	"llm_fixture":             "replay LLM responses from this fixture file instead of calling a provider",
+	"llm_record":              "record LLM responses to this fixture file",
+	"runs_dir":                "directory for run checkpoints, logs and artifacts",
+	"session":                 "load and save the browser session under this profile name",
+	"sessions_dir":            "directory for saved browser sessions",
+	"log_format":              "progress output format: text or json",
+	"output":                  "stdout content: text (progress and summary) or json (the task's output only)",
+	"artifact_mode":           "page snapshots to keep: steps, failures or off",
+	"trace":                   "record a Playwright trace into the run directory",
+	"video":                   "record a video of the browser into the run directory",
+}
@@ -0,0 +1,221 @@
+// Package e2e drives Agent.ExecuteTask end to end against the offline
+// mocksite storefront in headless Chromium. The LLM is replayed from the
+// fixtures in testdata, so a run needs neither network access nor an API
+// key.
+package e2e
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"browser-agent/internal/amazon_agent"
+	"browser-agent/internal/config"
+	"browser-agent/internal/llm"
+	"browser-agent/internal/mocksite"
+)
+
+// Scenario is one task run against the storefront. Its LLM responses are
+// replayed in order from testdata/<Name>.json.
+type Scenario struct {
+	Name  string
+	Task  string
+	Check func(site *mocksite.Server, result *amazon_agent.TaskResult) error
+	// Session runs the task twice with a saved session profile. The second
+	// run starts from the first run's cookies and must not ask for
+	// credentials; Check sees its result.
+	Session bool
+	// OutputSchema is given to the agent as its output_schema.
+	OutputSchema string
+	// CartReconcile, if set, overrides the config's cart_reconcile.
+	CartReconcile string
+}
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+// baseURLPlaceholder stands for the storefront's address in fixture
+// responses, since every scenario's storefront listens on its own port.
+const baseURLPlaceholder = "{{base_url}}"
+
+// LoadFixture returns the recorded LLM responses of scenario name, with
+// baseURLPlaceholder replaced by baseURL.
+func LoadFixture(name, baseURL string) (*llm.Fixture, error) {
+	data, err := fixtures.ReadFile("testdata/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("read fixture: %w", err)
+	}
+	var fixture llm.Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", name, err)
+	}
+	for i := range fixture.Interactions {
+		fixture.Interactions[i].Response = strings.ReplaceAll(fixture.Interactions[i].Response, baseURLPlaceholder, baseURL)
+	}
+	return &fixture, nil
+}
+
+type Result struct {
+	Scenario      string
+	Passed        bool
+	Err           error
+	StepsExecuted int
+	Duration      time.Duration
+}
+
+// Credentials and address answers given to every scenario's login and
+// fill_address steps.
+var Answers = amazon_agent.StaticPrompter{
+	"email":    "e2e@example.com",
+	"password": "e2e-password",
+	"fullname": "E2E Tester",
+	"phone":    "9999999999",
+	"pincode":  "411001",
+	"address1": "1 Test Street",
+	"address2": "Baner",
+	"city":     "Pune",
+	"state":    "Maharashtra",
+}
+
+// NewConfig returns the agent configuration used for e2e runs: headless,
+// no slow-mo and tighter limits than an interactive run. Progress checks
+// are off, since how many of them a run makes depends on timing and the
+// fixtures replay responses strictly in order.
+func NewConfig() *config.Config {
+	cfg := config.NewConfig()
+	cfg.Headless = true
+	cfg.SlowMo = 0
+	cfg.ValidationInterval = 0
+	cfg.MaxSteps = 50
+	cfg.TotalTimeout = 5 * time.Minute
+	cfg.RunsDir = filepath.Join(os.TempDir(), "browser-agent-e2e")
+	return cfg
+}
+
+// Run executes each scenario against its own storefront and browser.
+// Scenarios not yet started when ctx is cancelled are skipped.
+func Run(ctx context.Context, cfg *config.Config, scenarios []Scenario) []Result {
+	results := make([]Result, 0, len(scenarios))
+	for _, sc := range scenarios {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, runScenario(ctx, cfg, sc))
+	}
+	return results
+}
+
+func runScenario(ctx context.Context, cfg *config.Config, sc Scenario) Result {
+	start := time.Now()
+	res := Result{Scenario: sc.Name}
+
+	site := mocksite.NewServer(nil)
+	if err := site.Start(); err != nil {
+		res.Err = fmt.Errorf("start mock site: %w", err)
+		return res
+	}
+	defer site.Close()
+
+	fixture, err := LoadFixture(sc.Name, site.URL())
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	client, err := llm.NewScriptedClientFromFixture(fixture)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	runs := []amazon_agent.Prompter{Answers}
+	if sc.Session {
+		dir, err := os.MkdirTemp("", "browser-agent-sessions-")
+		if err != nil {
+			res.Err = fmt.Errorf("create sessions dir: %w", err)
+			return res
+		}
+		defer os.RemoveAll(dir)
+
+		sessionCfg := *cfg
+		sessionCfg.Session = sc.Name
+		sessionCfg.SessionsDir = dir
+		cfg = &sessionCfg
+		runs = append(runs, noCredentials{Answers})
+	}
+	if sc.CartReconcile != "" {
+		reconcileCfg := *cfg
+		reconcileCfg.CartReconcile = sc.CartReconcile
+		cfg = &reconcileCfg
+	}
+
+	var result *amazon_agent.TaskResult
+	for i, prompter := range runs {
+		var err error
+		result, err = runAgent(ctx, cfg, client, sc, prompter)
+		res.Duration = time.Since(start)
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		res.StepsExecuted += result.StepsExecuted
+
+		if !result.Success {
+			res.Err = fmt.Errorf("task failed (run %d of %d): %v", i+1, len(runs), result.Error)
+			return res
+		}
+	}
+
+	if calls, recorded := client.Calls(), len(fixture.Interactions); calls != recorded {
+		res.Err = fmt.Errorf("made %d LLM calls, fixture has %d responses", calls, recorded)
+		return res
+	}
+
+	if sc.Check != nil {
+		if err := sc.Check(site, result); err != nil {
+			res.Err = err
+			return res
+		}
+	}
+
+	res.Passed = true
+	return res
+}
+
+func runAgent(ctx context.Context, cfg *config.Config, client llm.Client, sc Scenario, prompter amazon_agent.Prompter) (*amazon_agent.TaskResult, error) {
+	if sc.OutputSchema != "" {
+		outputCfg := *cfg
+		outputCfg.OutputSchema = sc.OutputSchema
+		cfg = &outputCfg
+	}
+
+	agent, err := amazon_agent.NewAgent(cfg, client)
+	if err != nil {
+		return nil, fmt.Errorf("create agent: %w", err)
+	}
+	defer agent.Close()
+	agent.SetPrompter(prompter)
+
+	return agent.ExecuteTask(ctx, sc.Task)
+}
+
+// noCredentials answers address prompts but fails credential prompts, for
+// runs that must reuse a saved session.
+type noCredentials struct {
+	amazon_agent.StaticPrompter
+}
+
+func (p noCredentials) Prompt(ctx context.Context, field, label string) (string, error) {
+	if field == "email" {
+		return "", fmt.Errorf("e2e: asked for %s despite a saved session", field)
+	}
+	return p.StaticPrompter.Prompt(ctx, field, label)
+}
+
+func (p noCredentials) PromptSecret(ctx context.Context, field, label string) (string, error) {
+	return "", fmt.Errorf("e2e: asked for %s despite a saved session", field)
+}
@@ -0,0 +1,75 @@
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"browser-agent/internal/amazon_agent"
+	"browser-agent/internal/browser"
+)
+
+func TestE2E(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping browser runs in short mode")
+	}
+	b, err := browser.NewBrowser(browser.Options{Headless: true})
+	if err != nil {
+		t.Skipf("Playwright Chromium is not installed: %v", err)
+	}
+	b.Close()
+
+	cfg := NewConfig()
+	for _, sc := range Scenarios() {
+		t.Run(sc.Name, func(t *testing.T) {
+			res := runScenario(context.Background(), cfg, sc)
+			if !res.Passed {
+				t.Fatal(res.Err)
+			}
+			t.Logf("%d steps in %v", res.StepsExecuted, res.Duration)
+		})
+	}
+}
+
+// TestFixtures checks, without a browser, that every scenario has a
+// fixture and that the plans recorded in it pass plan validation.
+func TestFixtures(t *testing.T) {
+	validator := amazon_agent.NewPlanValidator(amazon_agent.NewExecutor(nil, nil, &amazon_agent.AgentMemory{}).Actions())
+
+	names := map[string]bool{}
+	for _, sc := range Scenarios() {
+		names[sc.Name] = true
+		fixture, err := LoadFixture(sc.Name, "http://127.0.0.1:8080")
+		if err != nil {
+			t.Errorf("%s: %v", sc.Name, err)
+			continue
+		}
+		plans := 0
+		for i, in := range fixture.Interactions {
+			var plan amazon_agent.Plan
+			if json.Unmarshal([]byte(in.Response), &plan) != nil || plan.Steps == nil {
+				continue
+			}
+			plans++
+			if err := validator.Validate(&plan); err != nil {
+				t.Errorf("%s: response %d: %v", sc.Name, i+1, err)
+			}
+		}
+		if plans == 0 {
+			t.Errorf("%s: fixture has no plan", sc.Name)
+		}
+	}
+
+	files, err := fs.Glob(fixtures, "testdata/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, "testdata/"), ".json")
+		if !names[name] {
+			t.Errorf("fixture %s belongs to no scenario", file)
+		}
+	}
+}
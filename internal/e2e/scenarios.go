@@ -0,0 +1,289 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"browser-agent/internal/amazon_agent"
+	"browser-agent/internal/mocksite"
+)
+
+// Scenarios is the default e2e suite, ordered from the shortest flow to the
+// full checkout.
+func Scenarios() []Scenario {
+	return []Scenario{
+		{
+			Name:  "search",
+			Task:  "Go to amazon.in and search for detergent",
+			Check: visited("/s"),
+		},
+		{
+			Name:  "add_to_cart",
+			Task:  "Search for detergent on amazon.in, select the first one and add to cart",
+			Check: inCart("B0MOCK0001"),
+		},
+		{
+			Name: "cheapest_across_pages",
+			Task: "Search for detergent on amazon.in and add the cheapest one to the cart",
+			// The cheapest detergent is on the second results page.
+			Check: inCart("B0MOCK0003"),
+		},
+		{
+			Name: "filter_and_sort",
+			Task: "Add the cheapest 4-star Prime mouse on amazon.in to the cart",
+			// Cheaper mice are rated under 4 stars or lack Prime.
+			Check: inCart("B0MOCK0101"),
+		},
+		{
+			Name: "select_variant",
+			Task: "Add a white Allen Solly polo t-shirt in size M from amazon.in to the cart",
+			// Search lists the black polo; white in M is a sibling variant.
+			Check: inCart("B0MOCK0503"),
+		},
+		{
+			Name:  "multi_item_cart",
+			Task:  "Add 2 USB cables and a charger to the cart on amazon.in",
+			Check: cartHolds(map[string]int{"B0MOCK0401": 2, "B0MOCK0402": 1}),
+		},
+		{
+			Name:  "edit_cart",
+			Task:  "Add 3 USB cables and a charger to the cart on amazon.in, then keep just one cable",
+			Check: cartHolds(map[string]int{"B0MOCK0401": 1}),
+		},
+		{
+			Name: "reconcile_cart",
+			Task: "Buy detergent from amazon.in and go to checkout",
+			// The plan also adds a product with a plain click the agent doesn't
+			// record, as a leftover from an earlier session would be;
+			// proceed_checkout removes it before checking out.
+			CartReconcile: amazon_agent.CartRepair,
+			Check:         all(cartHolds(map[string]int{"B0MOCK0001": 1}), visited("/ap/signin")),
+		},
+		{
+			Name:  "extract_product",
+			Task:  "Find the details of the first headphones on amazon.in",
+			Check: extracted("B0MOCK0201"),
+		},
+		{
+			Name:         "output_schema",
+			Task:         "Find the 4-star headphones on amazon.in, cheapest first, with price and link",
+			OutputSchema: productListSchema,
+			Check:        output("B0MOCK0201", "B0MOCK0202"),
+		},
+		{
+			Name: "collect_products",
+			Task: "List the detergents on amazon.in that aren't sponsored",
+			// One from each results page.
+			Check: collected("B0MOCK0001", "B0MOCK0003"),
+		},
+		{
+			Name: "checkout_to_payment",
+			Task: "Buy detergent from amazon.in, add to cart and go to payment screen",
+			Check: all(
+				inCart("B0MOCK0001"),
+				signedInAs(Answers["email"]),
+				addressCity(Answers["city"]),
+				visited("/checkout/payment"),
+			),
+		},
+		{
+			Name:    "saved_session",
+			Task:    "Buy detergent from amazon.in, add to cart and go to payment screen",
+			Session: true,
+			Check: all(
+				signedInAs(Answers["email"]),
+				sessionCount(1),
+				visited("/checkout/payment"),
+			),
+		},
+	}
+}
+
+// productListSchema is the output schema of tasks that list products.
+const productListSchema = `{
+  "type": "object",
+  "required": ["products"],
+  "additionalProperties": false,
+  "properties": {
+    "products": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "required": ["title", "price", "url"],
+        "properties": {
+          "title": {"type": "string", "minLength": 1},
+          "price": {"type": "number", "minimum": 0},
+          "url": {"type": "string", "format": "uri"}
+        }
+      }
+    }
+  }
+}`
+
+type check func(site *mocksite.Server, result *amazon_agent.TaskResult) error
+
+func all(checks ...check) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		for _, c := range checks {
+			if err := c(site, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func visited(path string) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		for _, sess := range site.Sessions() {
+			if slices.Contains(sess.Visited, path) {
+				return nil
+			}
+		}
+		return fmt.Errorf("storefront never served %s", path)
+	}
+}
+
+func inCart(asin string) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		for _, sess := range site.Sessions() {
+			for _, line := range sess.Cart {
+				if line.ASIN == asin {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("%s is not in the cart", asin)
+	}
+}
+
+// cartHolds checks that the storefront cart and the agent's memory of it
+// both hold exactly the given quantity of each ASIN.
+func cartHolds(want map[string]int) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		for _, sess := range site.Sessions() {
+			if len(sess.Cart) == 0 {
+				continue
+			}
+			got := make(map[string]int)
+			for _, line := range sess.Cart {
+				got[line.ASIN] = line.Quantity
+			}
+			if !maps.Equal(got, want) {
+				return fmt.Errorf("cart holds %v, want %v", got, want)
+			}
+
+			remembered := make(map[string]int)
+			if result.Memory != nil {
+				for _, line := range result.Memory.CartItems {
+					remembered[line.ASIN] = line.Quantity
+				}
+			}
+			if !maps.Equal(remembered, want) {
+				return fmt.Errorf("memory has cart %v, want %v", remembered, want)
+			}
+			return nil
+		}
+		return fmt.Errorf("the cart is empty")
+	}
+}
+
+func signedInAs(email string) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		for _, sess := range site.Sessions() {
+			if sess.SignedIn && sess.Email == email {
+				return nil
+			}
+		}
+		return fmt.Errorf("no session signed in as %s", email)
+	}
+}
+
+func addressCity(city string) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		for _, sess := range site.Sessions() {
+			if sess.Address["enterAddressCity"] == city {
+				return nil
+			}
+		}
+		return fmt.Errorf("no delivery address in %s was submitted", city)
+	}
+}
+
+// collected checks that the products collected into memory are exactly
+// asins, in order.
+func collected(asins ...string) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		var got []string
+		if result.Memory != nil {
+			for _, p := range result.Memory.Products {
+				got = append(got, p.ASIN)
+			}
+		}
+		if !slices.Equal(got, asins) {
+			return fmt.Errorf("collected products %v, want %v", got, asins)
+		}
+		return nil
+	}
+}
+
+// output checks that the task's output lists the products asins, in order,
+// by the links of their product pages.
+func output(asins ...string) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		var out struct {
+			Products []struct {
+				URL string `json:"url"`
+			} `json:"products"`
+		}
+		if err := json.Unmarshal(result.Output, &out); err != nil {
+			return fmt.Errorf("decode output %s: %w", result.Output, err)
+		}
+		if len(out.Products) != len(asins) {
+			return fmt.Errorf("output lists %d products, want %d: %s", len(out.Products), len(asins), result.Output)
+		}
+		for i, p := range out.Products {
+			if !strings.Contains(p.URL, "/dp/"+asins[i]) {
+				return fmt.Errorf("output product %d links to %s, want %s", i+1, p.URL, asins[i])
+			}
+		}
+		return nil
+	}
+}
+
+// extracted checks that memory holds the details of asin, with the fields
+// every mock product page shows filled in.
+func extracted(asin string) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		if result.Memory == nil {
+			return fmt.Errorf("no memory")
+		}
+		i := slices.IndexFunc(result.Memory.ProductDetails, func(d amazon_agent.ProductDetails) bool { return d.ASIN == asin })
+		if i < 0 {
+			return fmt.Errorf("no details extracted for %s", asin)
+		}
+		d := result.Memory.ProductDetails[i]
+		switch {
+		case d.Title == "", d.Price == 0, d.MRP == 0, d.Discount == 0, d.Rating == 0, d.Reviews == 0:
+			return fmt.Errorf("details of %s lack title, prices or rating: %+v", asin, d)
+		case d.Seller == "", d.Delivery == "", d.Availability == "", len(d.Bullets) == 0, len(d.Images) == 0:
+			return fmt.Errorf("details of %s lack seller, delivery, availability, bullets or images: %+v", asin, d)
+		}
+		return nil
+	}
+}
+
+// sessionCount checks how many storefront sessions were created; runs that
+// reuse a saved session share one.
+func sessionCount(n int) check {
+	return func(site *mocksite.Server, result *amazon_agent.TaskResult) error {
+		if got := len(site.Sessions()); got != n {
+			return fmt.Errorf("storefront saw %d sessions, want %d", got, n)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultAnthropicModel   = "claude-3-5-sonnet-latest"
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	anthropicVersion        = "2023-06-01"
+)
+
+// AnthropicClient talks to the native Anthropic Messages API.
+type AnthropicClient struct {
+	apiKey     string
+	httpClient *http.Client
+	model      string
+	apiURL     string
+}
+
+type anthropicRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens"`
+}
+
+type anthropicResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func NewAnthropicClient(opts Options) *AnthropicClient {
+	if opts.Model == "" {
+		opts.Model = defaultAnthropicModel
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultAnthropicBaseURL
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	return &AnthropicClient{
+		apiKey: opts.APIKey,
+		httpClient: &http.Client{
+			Timeout: opts.Timeout,
+		},
+		model:  opts.Model,
+		apiURL: strings.TrimSuffix(opts.BaseURL, "/") + "/v1/messages",
+	}
+}
+
+func (c *AnthropicClient) Generate(ctx context.Context, prompt string) (string, error) {
+	return generate(ctx, c, prompt)
+}
+
+func (c *AnthropicClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	req = withDefaults(req)
+
+	// The Messages API takes the system prompt separately and only accepts
+	// user/assistant turns.
+	system := req.System
+	messages := make([]Message, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			system = strings.TrimSpace(system + "\n\n" + m.Content)
+			continue
+		}
+		messages = append(messages, m)
+	}
+
+	reqBody := anthropicRequest{
+		Model:       c.model,
+		System:      system,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+
+	headers := map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	var resp anthropicResponse
+	if err := postJSON(ctx, c.httpClient, c.apiURL, headers, reqBody, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("API returned error: %s", resp.Error.Message)
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("no response generated")
+	}
+
+	return &ChatResponse{
+		Content: text.String(),
+		Model:   resp.Model,
+		Usage: Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+		},
+	}, nil
+}
@@ -0,0 +1,198 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Supported provider names for Options.Provider.
+const (
+	ProviderOpenRouter = "openrouter"
+	ProviderOpenAI     = "openai"
+	ProviderAnthropic  = "anthropic"
+	ProviderOllama     = "ollama"
+	// ProviderReplay serves responses from a fixture file, see ScriptedClient.
+	ProviderReplay = "replay"
+)
+
+const (
+	defaultTemperature = 0.7
+	defaultMaxTokens   = 2048
+	defaultTimeout     = 60 * time.Second
+)
+
+// Client is implemented by every LLM provider the agent can talk to.
+// Generate is a single-turn shortcut; Chat exposes system prompts,
+// multi-turn history and sampling options.
+type Client interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ChatRequest struct {
+	System   string
+	Messages []Message
+	// Temperature is nil for the default; use Temperature(0) for the most
+	// deterministic sampling.
+	Temperature *float64
+	MaxTokens   int
+}
+
+// Temperature returns t as a ChatRequest.Temperature.
+func Temperature(t float64) *float64 {
+	return &t
+}
+
+type ChatResponse struct {
+	Content string
+	Model   string
+	Usage   Usage
+}
+
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Options selects and configures a provider in NewClient. Empty Model and
+// BaseURL fall back to the provider defaults.
+type Options struct {
+	Provider string
+	APIKey   string
+	Model    string
+	BaseURL  string
+	Timeout  time.Duration
+
+	// FixturePath is the fixture file read by the replay provider.
+	FixturePath string
+	// RecordPath, when set, wraps the provider in a RecordingClient that
+	// captures every response to this file.
+	RecordPath string
+}
+
+func NewClient(opts Options) (Client, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+
+	var client Client
+	switch opts.Provider {
+	case "", ProviderOpenRouter:
+		client = NewOpenRouterClient(opts)
+	case ProviderOpenAI:
+		client = NewOpenAIClient(opts)
+	case ProviderAnthropic:
+		client = NewAnthropicClient(opts)
+	case ProviderOllama:
+		client = NewOllamaClient(opts)
+	case ProviderReplay:
+		if opts.FixturePath == "" {
+			return nil, fmt.Errorf("replay provider requires a fixture file")
+		}
+		scripted, err := NewScriptedClient(opts.FixturePath)
+		if err != nil {
+			return nil, err
+		}
+		client = scripted
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", opts.Provider)
+	}
+
+	if opts.RecordPath != "" {
+		if opts.Provider == ProviderReplay {
+			return nil, fmt.Errorf("cannot record while replaying")
+		}
+		client = NewRecordingClient(client, opts.RecordPath, MatchHash)
+	}
+	return client, nil
+}
+
+// APIKeyFromEnv returns the API key for provider from its environment
+// variables, in order of preference. Ollama needs no key.
+func APIKeyFromEnv(provider string) (key string, envVars []string) {
+	switch provider {
+	case "", ProviderOpenRouter:
+		envVars = []string{"OPENROUTER_API_KEY", "GEMINI_API_KEY"}
+	case ProviderOpenAI:
+		envVars = []string{"OPENAI_API_KEY"}
+	case ProviderAnthropic:
+		envVars = []string{"ANTHROPIC_API_KEY"}
+	}
+
+	for _, name := range envVars {
+		if v := os.Getenv(name); v != "" {
+			return v, envVars
+		}
+	}
+	return "", envVars
+}
+
+// generate runs a single user prompt through c.Chat with the default
+// sampling options.
+func generate(ctx context.Context, c Client, prompt string) (string, error) {
+	resp, err := c.Chat(ctx, ChatRequest{
+		Messages: []Message{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+func withDefaults(req ChatRequest) ChatRequest {
+	if req.Temperature == nil {
+		req.Temperature = Temperature(defaultTemperature)
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = defaultMaxTokens
+	}
+	return req
+}
+
+func postJSON(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, in, out interface{}) error {
+	jsonData, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOllamaModel   = "llama3.1"
+	defaultOllamaBaseURL = "http://localhost:11434"
+)
+
+// OllamaClient talks to a local Ollama server through its /api/chat
+// endpoint with streaming disabled.
+type OllamaClient struct {
+	httpClient *http.Client
+	model      string
+	apiURL     string
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+type ollamaResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error,omitempty"`
+}
+
+func NewOllamaClient(opts Options) *OllamaClient {
+	if opts.Model == "" {
+		opts.Model = defaultOllamaModel
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultOllamaBaseURL
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	return &OllamaClient{
+		httpClient: &http.Client{
+			Timeout: opts.Timeout,
+		},
+		model:  opts.Model,
+		apiURL: strings.TrimSuffix(opts.BaseURL, "/") + "/api/chat",
+	}
+}
+
+func (c *OllamaClient) Generate(ctx context.Context, prompt string) (string, error) {
+	return generate(ctx, c, prompt)
+}
+
+func (c *OllamaClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	req = withDefaults(req)
+
+	messages := make([]Message, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, Message{Role: "system", Content: req.System})
+	}
+	messages = append(messages, req.Messages...)
+
+	reqBody := ollamaRequest{
+		Model:    c.model,
+		Messages: messages,
+		Stream:   false,
+		Options: ollamaOptions{
+			Temperature: req.Temperature,
+			NumPredict:  req.MaxTokens,
+		},
+	}
+
+	var resp ollamaResponse
+	if err := postJSON(ctx, c.httpClient, c.apiURL, nil, reqBody, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return nil, fmt.Errorf("API returned error: %s", resp.Error)
+	}
+
+	if resp.Message.Content == "" {
+		return nil, fmt.Errorf("no response generated")
+	}
+
+	return &ChatResponse{
+		Content: resp.Message.Content,
+		Model:   resp.Model,
+		Usage: Usage{
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+		},
+	}, nil
+}
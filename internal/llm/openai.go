@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOpenAIModel   = "gpt-4o-mini"
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+)
+
+// OpenAIClient talks to any endpoint implementing the OpenAI chat
+// completions API.
+type OpenAIClient struct {
+	apiKey     string
+	httpClient *http.Client
+	model      string
+	apiURL     string
+	headers    map[string]string
+}
+
+type chatCompletionRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+}
+
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func NewOpenAIClient(opts Options) *OpenAIClient {
+	if opts.Model == "" {
+		opts.Model = defaultOpenAIModel
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultOpenAIBaseURL
+	}
+	return newChatCompletionClient(opts, nil)
+}
+
+func newChatCompletionClient(opts Options, headers map[string]string) *OpenAIClient {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultTimeout
+	}
+	return &OpenAIClient{
+		apiKey: opts.APIKey,
+		httpClient: &http.Client{
+			Timeout: opts.Timeout,
+		},
+		model:   opts.Model,
+		apiURL:  strings.TrimSuffix(opts.BaseURL, "/") + "/chat/completions",
+		headers: headers,
+	}
+}
+
+func (c *OpenAIClient) Generate(ctx context.Context, prompt string) (string, error) {
+	return generate(ctx, c, prompt)
+}
+
+func (c *OpenAIClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	req = withDefaults(req)
+
+	messages := make([]Message, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, Message{Role: "system", Content: req.System})
+	}
+	messages = append(messages, req.Messages...)
+
+	reqBody := chatCompletionRequest{
+		Model:       c.model,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+
+	headers := map[string]string{}
+	if c.apiKey != "" {
+		headers["Authorization"] = "Bearer " + c.apiKey
+	}
+	for k, v := range c.headers {
+		headers[k] = v
+	}
+
+	var resp chatCompletionResponse
+	if err := postJSON(ctx, c.httpClient, c.apiURL, headers, reqBody, &resp); err != nil {
+		return nil, err
+	}
+
+	// Check for API errors
+	if resp.Error != nil {
+		return nil, fmt.Errorf("API returned error: %s", resp.Error.Message)
+	}
+
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return nil, fmt.Errorf("no response generated")
+	}
+
+	return &ChatResponse{
+		Content: resp.Choices[0].Message.Content,
+		Model:   resp.Model,
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+		},
+	}, nil
+}
@@ -0,0 +1,21 @@
+package llm
+
+const (
+	defaultOpenRouterModel   = "anthropic/claude-3.5-sonnet"
+	defaultOpenRouterBaseURL = "https://openrouter.ai/api/v1"
+)
+
+// NewOpenRouterClient returns an OpenAI-compatible client pointed at
+// OpenRouter, with the attribution headers OpenRouter expects.
+func NewOpenRouterClient(opts Options) *OpenAIClient {
+	if opts.Model == "" {
+		opts.Model = defaultOpenRouterModel
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = defaultOpenRouterBaseURL
+	}
+	return newChatCompletionClient(opts, map[string]string{
+		"HTTP-Referer": "https://browser-agent.com",
+		"X-Title":      "Browser Agent",
+	})
+}
@@ -0,0 +1,257 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Fixture match modes.
+const (
+	// MatchHash serves the response recorded for the exact same prompt.
+	// Repeated prompts are answered in recording order.
+	MatchHash = "hash"
+	// MatchSequence serves recorded responses in call order and ignores
+	// the prompt text, which suits prompts containing volatile page state.
+	MatchSequence = "sequence"
+)
+
+// ErrNoRecording is returned (wrapped in a *NoRecordingError) when a
+// scripted client has no response for a prompt.
+var ErrNoRecording = errors.New("no recorded response")
+
+// Fixture is the on-disk format shared by ScriptedClient and
+// RecordingClient.
+type Fixture struct {
+	Match        string        `json:"match"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	PromptHash string `json:"prompt_hash"`
+	Prompt     string `json:"prompt,omitempty"`
+	Response   string `json:"response"`
+}
+
+type NoRecordingError struct {
+	Mode       string
+	Call       int
+	PromptHash string
+	Prompt     string
+}
+
+func (e *NoRecordingError) Error() string {
+	return fmt.Sprintf("%v for prompt %s (call #%d, %s mode): %q",
+		ErrNoRecording, e.PromptHash[:12], e.Call, e.Mode, previewPrompt(e.Prompt, 120))
+}
+
+func (e *NoRecordingError) Unwrap() error {
+	return ErrNoRecording
+}
+
+// ScriptedClient replays responses from a Fixture without any network
+// access. It is deterministic and safe for concurrent use.
+type ScriptedClient struct {
+	mu       sync.Mutex
+	fixture  *Fixture
+	calls    int
+	byHash   map[string][]string
+	consumed map[string]int
+}
+
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fixture: %w", err)
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal fixture: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write fixture: %w", err)
+	}
+	return nil
+}
+
+func NewScriptedClient(path string) (*ScriptedClient, error) {
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewScriptedClientFromFixture(fixture)
+}
+
+func NewScriptedClientFromFixture(fixture *Fixture) (*ScriptedClient, error) {
+	if fixture.Match == "" {
+		fixture.Match = MatchHash
+	}
+	if fixture.Match != MatchHash && fixture.Match != MatchSequence {
+		return nil, fmt.Errorf("unknown fixture match mode %q", fixture.Match)
+	}
+
+	c := &ScriptedClient{
+		fixture:  fixture,
+		byHash:   make(map[string][]string),
+		consumed: make(map[string]int),
+	}
+	for _, in := range fixture.Interactions {
+		c.byHash[in.PromptHash] = append(c.byHash[in.PromptHash], in.Response)
+	}
+	return c, nil
+}
+
+// NewSequenceClient is a convenience for in-code scripts: each response is
+// served once, in order.
+func NewSequenceClient(responses ...string) *ScriptedClient {
+	fixture := &Fixture{Match: MatchSequence}
+	for _, r := range responses {
+		fixture.Interactions = append(fixture.Interactions, Interaction{Response: r})
+	}
+	c, _ := NewScriptedClientFromFixture(fixture)
+	return c
+}
+
+func (c *ScriptedClient) Generate(ctx context.Context, prompt string) (string, error) {
+	return generate(ctx, c, prompt)
+}
+
+func (c *ScriptedClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+	prompt := PromptText(req)
+	hash := PromptHash(req)
+
+	notFound := &NoRecordingError{
+		Mode:       c.fixture.Match,
+		Call:       c.calls,
+		PromptHash: hash,
+		Prompt:     prompt,
+	}
+
+	switch c.fixture.Match {
+	case MatchSequence:
+		if c.calls > len(c.fixture.Interactions) {
+			return nil, notFound
+		}
+		return &ChatResponse{Content: c.fixture.Interactions[c.calls-1].Response, Model: "scripted"}, nil
+	default:
+		responses := c.byHash[hash]
+		if len(responses) == 0 {
+			return nil, notFound
+		}
+		// Once a repeated prompt runs out of recordings, keep answering
+		// with the last one rather than failing.
+		idx := min(c.consumed[hash], len(responses)-1)
+		c.consumed[hash]++
+		return &ChatResponse{Content: responses[idx], Model: "scripted"}, nil
+	}
+}
+
+// Calls returns the number of Chat/Generate calls served so far.
+func (c *ScriptedClient) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// RecordingClient forwards every call to a real provider and appends the
+// prompt hash and response to a fixture file, which can later be replayed
+// with ScriptedClient.
+type RecordingClient struct {
+	mu      sync.Mutex
+	inner   Client
+	path    string
+	fixture *Fixture
+}
+
+func NewRecordingClient(inner Client, path string, match string) *RecordingClient {
+	if match == "" {
+		match = MatchHash
+	}
+	return &RecordingClient{
+		inner:   inner,
+		path:    path,
+		fixture: &Fixture{Match: match},
+	}
+}
+
+func (c *RecordingClient) Generate(ctx context.Context, prompt string) (string, error) {
+	return generate(ctx, c, prompt)
+}
+
+func (c *RecordingClient) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	resp, err := c.inner.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.fixture.Interactions = append(c.fixture.Interactions, Interaction{
+		PromptHash: PromptHash(req),
+		Prompt:     previewPrompt(PromptText(req), 200),
+		Response:   resp.Content,
+	})
+
+	// Save after every call so a crashed run still leaves a usable fixture.
+	if err := c.fixture.Save(c.path); err != nil {
+		return nil, fmt.Errorf("record response: %w", err)
+	}
+
+	return resp, nil
+}
+
+// PromptText flattens a request into the canonical text that PromptHash
+// digests.
+func PromptText(req ChatRequest) string {
+	var b strings.Builder
+	if req.System != "" {
+		b.WriteString("system: ")
+		b.WriteString(req.System)
+		b.WriteString("\n")
+	}
+	for _, m := range req.Messages {
+		b.WriteString(m.Role)
+		b.WriteString(": ")
+		b.WriteString(m.Content)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func PromptHash(req ChatRequest) string {
+	sum := sha256.Sum256([]byte(PromptText(req)))
+	return hex.EncodeToString(sum[:])
+}
+
+// previewPrompt is the start of prompt, up to n runes, on one line.
+func previewPrompt(prompt string, n int) string {
+	prompt = strings.Join(strings.Fields(prompt), " ")
+	if runes := []rune(prompt); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return prompt
+}
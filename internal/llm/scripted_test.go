@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func userRequest(prompt string) ChatRequest {
+	return ChatRequest{Messages: []Message{{Role: "user", Content: prompt}}}
+}
+
+func TestScriptedClientSequence(t *testing.T) {
+	c := NewSequenceClient("first", "second")
+	ctx := context.Background()
+
+	for _, want := range []string{"first", "second"} {
+		got, err := c.Generate(ctx, "prompts are ignored in sequence mode")
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if got != want {
+			t.Errorf("Generate = %q, want %q", got, want)
+		}
+	}
+
+	_, err := c.Generate(ctx, "one call too many")
+	var noRec *NoRecordingError
+	if !errors.As(err, &noRec) || !errors.Is(err, ErrNoRecording) {
+		t.Fatalf("third call: got %v, want a *NoRecordingError", err)
+	}
+	if noRec.Call != 3 || noRec.Mode != MatchSequence {
+		t.Errorf("error reports call %d in %s mode, want call 3 in sequence mode", noRec.Call, noRec.Mode)
+	}
+	if c.Calls() != 3 {
+		t.Errorf("Calls = %d, want 3", c.Calls())
+	}
+}
+
+func TestScriptedClientHash(t *testing.T) {
+	fixture := &Fixture{Match: MatchHash, Interactions: []Interaction{
+		{PromptHash: PromptHash(userRequest("plan")), Response: "plan 1"},
+		{PromptHash: PromptHash(userRequest("validate")), Response: "valid"},
+		{PromptHash: PromptHash(userRequest("plan")), Response: "plan 2"},
+	}}
+	c, err := NewScriptedClientFromFixture(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Repeated prompts get their recordings in order, then the last one.
+	for _, tc := range []struct{ prompt, want string }{
+		{"validate", "valid"},
+		{"plan", "plan 1"},
+		{"plan", "plan 2"},
+		{"plan", "plan 2"},
+	} {
+		got, err := c.Generate(ctx, tc.prompt)
+		if err != nil {
+			t.Fatalf("Generate(%q): %v", tc.prompt, err)
+		}
+		if got != tc.want {
+			t.Errorf("Generate(%q) = %q, want %q", tc.prompt, got, tc.want)
+		}
+	}
+
+	_, err = c.Generate(ctx, "never recorded")
+	var noRec *NoRecordingError
+	if !errors.As(err, &noRec) {
+		t.Fatalf("unrecorded prompt: got %v, want a *NoRecordingError", err)
+	}
+	if noRec.PromptHash != PromptHash(userRequest("never recorded")) || noRec.Mode != MatchHash {
+		t.Errorf("error = %+v, want the prompt's hash in hash mode", noRec)
+	}
+}
+
+func TestScriptedClientUnknownMode(t *testing.T) {
+	if _, err := NewScriptedClientFromFixture(&Fixture{Match: "fuzzy"}); err == nil {
+		t.Fatal("want an error for an unknown match mode")
+	}
+}
+
+func TestScriptedClientCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewSequenceClient("unused").Generate(ctx, "prompt"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestRecordingClientReplays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	ctx := context.Background()
+
+	rec := NewRecordingClient(NewSequenceClient("answer a", "answer b"), path, MatchHash)
+	for _, prompt := range []string{"prompt a", "prompt b"} {
+		if _, err := rec.Generate(ctx, prompt); err != nil {
+			t.Fatalf("record %q: %v", prompt, err)
+		}
+	}
+
+	replay, err := NewScriptedClient(path)
+	if err != nil {
+		t.Fatalf("load recorded fixture: %v", err)
+	}
+	// Out of recording order: hash mode matches by prompt.
+	for _, tc := range []struct{ prompt, want string }{
+		{"prompt b", "answer b"},
+		{"prompt a", "answer a"},
+	} {
+		got, err := replay.Generate(ctx, tc.prompt)
+		if err != nil {
+			t.Fatalf("replay %q: %v", tc.prompt, err)
+		}
+		if got != tc.want {
+			t.Errorf("replay %q = %q, want %q", tc.prompt, got, tc.want)
+		}
+	}
+}
+
+func TestRecordingClientKeepsErrorsOut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	rec := NewRecordingClient(NewSequenceClient(), path, MatchSequence)
+	if _, err := rec.Generate(context.Background(), "prompt"); err == nil {
+		t.Fatal("want the inner client's error")
+	}
+	if len(rec.fixture.Interactions) != 0 {
+		t.Errorf("recorded %d interactions of a failed call", len(rec.fixture.Interactions))
+	}
+}
+
+func TestWithDefaultsKeepsZeroTemperature(t *testing.T) {
+	if got := withDefaults(ChatRequest{}).Temperature; got == nil || *got != defaultTemperature {
+		t.Errorf("unset temperature = %v, want the default %v", got, defaultTemperature)
+	}
+	if got := withDefaults(ChatRequest{Temperature: Temperature(0)}).Temperature; got == nil || *got != 0 {
+		t.Errorf("temperature 0 = %v, want 0", got)
+	}
+}
+
+func TestPreviewPromptCutsRunes(t *testing.T) {
+	got := previewPrompt("Buy  the cheapest\nडिटर्जेंट under ₹500", 20)
+	if want := "Buy the cheapest डिट..."; got != want {
+		t.Errorf("previewPrompt = %q, want %q", got, want)
+	}
+}
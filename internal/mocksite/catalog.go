@@ -0,0 +1,100 @@
+package mocksite
+
+import "strings"
+
+// Product is one item of the stand-in storefront's catalog.
+type Product struct {
+	ASIN      string
+	Title     string
+	Brand     string
+	Price     float64
+	MRP       float64
+	Rating    float64
+	Reviews   int
+	Prime     bool
+	Sponsored bool
+	Keywords  []string
+
+	// Seller is who the product is sold by; Cocoblu Retail if unset.
+	// Bullets are the "About this item" points of the product page.
+	Seller  string
+	Bullets []string
+
+	// Family groups the variants of one product and Variation is this
+	// variant's option for each of the family's dimensions, such as
+	// Colour: Black. Search lists only the first variant of a family.
+	Family     string
+	Variation  []Option
+	OutOfStock bool
+}
+
+// Option is a product's value for one variation dimension.
+type Option struct {
+	Name  string
+	Value string
+}
+
+// DefaultCatalog covers the product categories used in the example tasks.
+var DefaultCatalog = []Product{
+	{ASIN: "B0MOCK0001", Title: "Surf Excel Matic Liquid Detergent 2L", Brand: "Surf Excel", Price: 399, MRP: 499, Rating: 4.4, Reviews: 18234, Prime: true, Keywords: []string{"detergent", "laundry", "liquid"},
+		Bullets: []string{"Specially designed for front and top load washing machines", "Removes tough stains in one wash", "2L pack, about 30 washes"}},
+	{ASIN: "B0MOCK0002", Title: "Ariel Matic Top Load Detergent Powder 4kg", Brand: "Ariel", Price: 849, MRP: 1099, Rating: 4.3, Reviews: 9120, Prime: true, Sponsored: true, Keywords: []string{"detergent", "laundry", "powder"}},
+	{ASIN: "B0MOCK0003", Title: "Tide Plus Double Power Detergent Powder 2kg", Brand: "Tide", Price: 249, MRP: 300, Rating: 3.9, Reviews: 4410, Keywords: []string{"detergent", "laundry", "powder"}},
+	{ASIN: "B0MOCK0101", Title: "Logitech M235 Wireless Mouse", Brand: "Logitech", Price: 749, MRP: 1295, Rating: 4.4, Reviews: 52011, Prime: true, Keywords: []string{"mouse", "wireless", "computer"},
+		Seller: "Appario Retail", Bullets: []string{"2.4 GHz wireless with nano receiver", "12-month battery life", "Works with Windows, macOS and Chrome OS"}},
+	{ASIN: "B0MOCK0102", Title: "HP X200 Wireless Mouse", Brand: "HP", Price: 499, MRP: 899, Rating: 4.0, Reviews: 12890, Keywords: []string{"mouse", "wireless", "computer"}},
+	{ASIN: "B0MOCK0103", Title: "Zebronics Zeb-Transformer-M Gaming Mouse", Brand: "Zebronics", Price: 349, MRP: 799, Rating: 3.8, Reviews: 7303, Sponsored: true, Keywords: []string{"mouse", "gaming", "computer"}},
+	{ASIN: "B0MOCK0201", Title: "boAt Rockerz 450 Bluetooth On Ear Headphones", Brand: "boAt", Price: 1499, MRP: 3990, Rating: 4.1, Reviews: 98432, Prime: true, Keywords: []string{"headphones", "bluetooth", "audio"},
+		Bullets: []string{"40mm dynamic drivers", "Up to 15 hours of playback", "Padded ear cushions"}},
+	{ASIN: "B0MOCK0202", Title: "Sony WH-CH520 Wireless Headphones", Brand: "Sony", Price: 4490, MRP: 5990, Rating: 4.5, Reviews: 15120, Prime: true, Keywords: []string{"headphones", "wireless", "audio"},
+		Seller: "Appario Retail", Bullets: []string{"Up to 50 hours of battery life", "Quick charge: 3 minutes for 1 hour of playback", "Multipoint connection"}},
+	{ASIN: "B0MOCK0301", Title: "Spigen Ultra Hybrid Smartphone Case", Brand: "Spigen", Price: 999, MRP: 1899, Rating: 4.3, Reviews: 6021, Prime: true, Keywords: []string{"smartphone", "case", "phone", "cover"},
+		Family: "spigen-ultra-hybrid", Variation: []Option{{"Colour", "Matte Black"}}},
+	{ASIN: "B0MOCK0302", Title: "Spigen Ultra Hybrid Smartphone Case", Brand: "Spigen", Price: 949, MRP: 1899, Rating: 4.3, Reviews: 6021, Prime: true, Keywords: []string{"smartphone", "case", "phone", "cover"},
+		Family: "spigen-ultra-hybrid", Variation: []Option{{"Colour", "Crystal Clear"}}},
+	{ASIN: "B0MOCK0303", Title: "Spigen Ultra Hybrid Smartphone Case", Brand: "Spigen", Price: 1049, MRP: 1899, Rating: 4.3, Reviews: 6021, Prime: true, Keywords: []string{"smartphone", "case", "phone", "cover"},
+		Family: "spigen-ultra-hybrid", Variation: []Option{{"Colour", "Navy Blue"}}, OutOfStock: true},
+	{ASIN: "B0MOCK0401", Title: "Amazon Basics USB-C Cable 1m", Brand: "Amazon Basics", Price: 299, MRP: 699, Rating: 4.2, Reviews: 30112, Prime: true, Keywords: []string{"usb", "cable", "charger"}},
+	{ASIN: "B0MOCK0402", Title: "Anker 20W USB-C Fast Charger", Brand: "Anker", Price: 1099, MRP: 1999, Rating: 4.4, Reviews: 8112, Prime: true, Keywords: []string{"charger", "usb", "adapter"}},
+	{ASIN: "B0MOCK0501", Title: "Allen Solly Men's Regular Fit Polo T-Shirt", Brand: "Allen Solly", Price: 599, MRP: 999, Rating: 4.1, Reviews: 3120, Prime: true, Keywords: []string{"tshirt", "t-shirt", "polo", "shirt"},
+		Family: "allen-solly-polo", Variation: []Option{{"Colour", "Black"}, {"Size", "M"}}},
+	{ASIN: "B0MOCK0502", Title: "Allen Solly Men's Regular Fit Polo T-Shirt", Brand: "Allen Solly", Price: 599, MRP: 999, Rating: 4.1, Reviews: 3120, Prime: true, Keywords: []string{"tshirt", "t-shirt", "polo", "shirt"},
+		Family: "allen-solly-polo", Variation: []Option{{"Colour", "Black"}, {"Size", "L"}}},
+	{ASIN: "B0MOCK0503", Title: "Allen Solly Men's Regular Fit Polo T-Shirt", Brand: "Allen Solly", Price: 649, MRP: 999, Rating: 4.1, Reviews: 3120, Prime: true, Keywords: []string{"tshirt", "t-shirt", "polo", "shirt"},
+		Family: "allen-solly-polo", Variation: []Option{{"Colour", "White"}, {"Size", "M"}}},
+	{ASIN: "B0MOCK0504", Title: "Allen Solly Men's Regular Fit Polo T-Shirt", Brand: "Allen Solly", Price: 649, MRP: 999, Rating: 4.1, Reviews: 3120, Prime: true, Keywords: []string{"tshirt", "t-shirt", "polo", "shirt"},
+		Family: "allen-solly-polo", Variation: []Option{{"Colour", "White"}, {"Size", "L"}}, OutOfStock: true},
+}
+
+func (s *Server) search(query string) []Product {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return s.catalog
+	}
+
+	var results []Product
+	families := make(map[string]bool)
+	for _, p := range s.catalog {
+		if p.Family != "" && families[p.Family] {
+			continue
+		}
+		haystack := strings.ToLower(p.Title + " " + p.Brand + " " + strings.Join(p.Keywords, " "))
+		for _, term := range terms {
+			if strings.Contains(haystack, strings.TrimSuffix(term, "s")) {
+				results = append(results, p)
+				families[p.Family] = true
+				break
+			}
+		}
+	}
+	return results
+}
+
+func (s *Server) product(asin string) (Product, bool) {
+	for _, p := range s.catalog {
+		if p.ASIN == asin {
+			return p, true
+		}
+	}
+	return Product{}, false
+}
@@ -0,0 +1,256 @@
+package mocksite
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// resultsPerPage is kept small so that even the short result lists of the
+// catalog span several pages.
+const resultsPerPage = 2
+
+// Refinement ids in the rh parameter, as on amazon.in: p_36 is a price
+// range in paise ("50000-200000"), p_72 a minimum customer review, p_85
+// Prime and p_89 a brand.
+const (
+	refinePrice  = "p_36"
+	refineRating = "p_72"
+	refinePrime  = "p_85"
+	refineBrand  = "p_89"
+
+	primeNode = "10440599031"
+)
+
+// ratingNodes are the p_72 values of "N Stars & Up".
+var ratingNodes = map[string]float64{
+	"1318476031": 4,
+	"1318477031": 3,
+	"1318478031": 2,
+	"1318479031": 1,
+}
+
+// sortOrders are the s parameter values of the sort dropdown.
+var sortOrders = []sortOrder{
+	{"relevanceblender", "Featured"},
+	{"price-asc-rank", "Price: Low to High"},
+	{"price-desc-rank", "Price: High to Low"},
+	{"review-rank", "Avg. Customer Review"},
+	{"date-desc-rank", "Newest Arrivals"},
+}
+
+type sortOrder struct {
+	Value string
+	Label string
+}
+
+type pagination struct {
+	Current        int
+	Links          []pageLink
+	Previous, Next string
+}
+
+type pageLink struct {
+	Number int
+	URL    string
+}
+
+// refinements is the sidebar and sort dropdown of a results page.
+type refinements struct {
+	Query     string
+	Ratings   []refinementLink
+	Prime     refinementLink
+	Brands    []refinementLink
+	LowPrice  string
+	HighPrice string
+	// RH is the current rh parameter, kept by the price form and the sort
+	// dropdown.
+	RH    string
+	Sort  string
+	Sorts []sortOrder
+}
+
+type refinementLink struct {
+	Label    string
+	URL      string
+	Selected bool
+}
+
+// searchFilter is what the rh, low-price and high-price parameters ask for.
+type searchFilter struct {
+	minPrice, maxPrice float64
+	minRating          float64
+	prime              bool
+	brands             []string
+}
+
+func parseSearchFilter(q url.Values) searchFilter {
+	var f searchFilter
+	for _, part := range strings.Split(q.Get("rh"), ",") {
+		key, value, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case refinePrice:
+			low, high, _ := strings.Cut(value, "-")
+			f.minPrice = paise(low)
+			f.maxPrice = paise(high)
+		case refineRating:
+			f.minRating = ratingNodes[value]
+		case refinePrime:
+			f.prime = value == primeNode
+		case refineBrand:
+			f.brands = append(f.brands, strings.Split(value, "|")...)
+		}
+	}
+	if v, err := strconv.ParseFloat(q.Get("low-price"), 64); err == nil {
+		f.minPrice = v
+	}
+	if v, err := strconv.ParseFloat(q.Get("high-price"), 64); err == nil {
+		f.maxPrice = v
+	}
+	return f
+}
+
+func paise(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v / 100
+}
+
+func (f searchFilter) matches(p Product) bool {
+	switch {
+	case f.minPrice > 0 && p.Price < f.minPrice,
+		f.maxPrice > 0 && p.Price > f.maxPrice,
+		p.Rating < f.minRating,
+		f.prime && !p.Prime:
+		return false
+	}
+	return len(f.brands) == 0 || slices.ContainsFunc(f.brands, func(b string) bool { return strings.EqualFold(b, p.Brand) })
+}
+
+func sortProducts(products []Product, order string) {
+	switch order {
+	case "price-asc-rank":
+		sort.SliceStable(products, func(i, j int) bool { return products[i].Price < products[j].Price })
+	case "price-desc-rank":
+		sort.SliceStable(products, func(i, j int) bool { return products[i].Price > products[j].Price })
+	case "review-rank":
+		sort.SliceStable(products, func(i, j int) bool { return products[i].Rating > products[j].Rating })
+	case "date-desc-rank":
+		// The catalog lists older products first.
+		slices.Reverse(products)
+	}
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := q.Get("k")
+	if query == "" {
+		query = q.Get("field-keywords")
+		q.Set("k", query)
+	}
+
+	matching := s.search(query)
+	filter := parseSearchFilter(q)
+	var results []Product
+	for _, p := range matching {
+		if filter.matches(p) {
+			results = append(results, p)
+		}
+	}
+	sortProducts(results, q.Get("s"))
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	pages := (len(results) + resultsPerPage - 1) / resultsPerPage
+	page = max(1, min(page, pages))
+
+	data := pageData{
+		Title:   "Amazon.in : " + query,
+		Query:   query,
+		Total:   len(results),
+		Filters: newRefinements(q, matching, filter),
+	}
+	if pages > 1 {
+		data.Pages = &pagination{Current: page}
+		for i := 1; i <= pages; i++ {
+			data.Pages.Links = append(data.Pages.Links, pageLink{Number: i, URL: searchURL(q, "page", strconv.Itoa(i))})
+		}
+		if page > 1 {
+			data.Pages.Previous = searchURL(q, "page", strconv.Itoa(page-1))
+		}
+		if page < pages {
+			data.Pages.Next = searchURL(q, "page", strconv.Itoa(page+1))
+		}
+	}
+	if len(results) > 0 {
+		data.Results = results[(page-1)*resultsPerPage : min(page*resultsPerPage, len(results))]
+	}
+	s.render(w, r, "search.html", data)
+}
+
+// newRefinements builds the sidebar links for the products matching the
+// query, each adding its refinement to the current ones (or replacing the
+// current one of its kind).
+func newRefinements(q url.Values, matching []Product, filter searchFilter) *refinements {
+	rh := q.Get("rh")
+	refine := func(entry string) string {
+		key, _, _ := strings.Cut(entry, ":")
+		entries := []string{entry}
+		for _, part := range strings.Split(rh, ",") {
+			if part != "" && !strings.HasPrefix(part, key+":") {
+				entries = append(entries, part)
+			}
+		}
+		return searchURL(q, "rh", strings.Join(entries, ","))
+	}
+
+	f := &refinements{Query: q.Get("k"), RH: rh, Sort: q.Get("s"), Sorts: sortOrders}
+	for node, stars := range ratingNodes {
+		f.Ratings = append(f.Ratings, refinementLink{
+			Label:    strconv.Itoa(int(stars)) + " Stars & Up",
+			URL:      refine(refineRating + ":" + node),
+			Selected: filter.minRating == stars,
+		})
+	}
+	sort.Slice(f.Ratings, func(i, j int) bool { return f.Ratings[i].Label > f.Ratings[j].Label })
+	f.Prime = refinementLink{Label: "Prime", URL: refine(refinePrime + ":" + primeNode), Selected: filter.prime}
+
+	var brands []string
+	for _, p := range matching {
+		if !slices.Contains(brands, p.Brand) {
+			brands = append(brands, p.Brand)
+		}
+	}
+	for _, brand := range brands {
+		f.Brands = append(f.Brands, refinementLink{
+			Label:    brand,
+			URL:      refine(refineBrand + ":" + brand),
+			Selected: slices.ContainsFunc(filter.brands, func(b string) bool { return strings.EqualFold(b, brand) }),
+		})
+	}
+	if filter.minPrice > 0 {
+		f.LowPrice = strconv.FormatFloat(filter.minPrice, 'f', -1, 64)
+	}
+	if filter.maxPrice > 0 {
+		f.HighPrice = strconv.FormatFloat(filter.maxPrice, 'f', -1, 64)
+	}
+	return f
+}
+
+// searchURL is the results URL for q with key set to value, back on the
+// first page unless key is the page.
+func searchURL(q url.Values, key, value string) string {
+	next := url.Values{}
+	for k, v := range q {
+		next[k] = v
+	}
+	if key != "page" {
+		next.Del("page")
+	}
+	next.Set(key, value)
+	return "/s?" + next.Encode()
+}
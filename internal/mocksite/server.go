@@ -0,0 +1,419 @@
+// Package mocksite serves an offline stand-in for the Amazon storefront.
+// Pages reuse the DOM ids the executor targets (#twotabsearchtextbox,
+// #add-to-cart-button, #sc-buy-box-ptc-button, #ap_email, the
+// address-ui-widgets form, ...) so the full agent flow can run against it.
+package mocksite
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+const sessionCookie = "session-id"
+
+type Server struct {
+	catalog []Product
+	tmpl    *template.Template
+	mux     *http.ServeMux
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+
+	listener   net.Listener
+	httpServer *http.Server
+}
+
+// Session is the per-browser state the storefront keeps, exposed so tests
+// can assert on what the agent actually did.
+type Session struct {
+	ID       string
+	Email    string
+	SignedIn bool
+	Cart     []CartLine
+	Address  map[string]string
+	Visited  []string
+}
+
+type CartLine struct {
+	ASIN     string
+	Title    string
+	Price    float64
+	Quantity int
+}
+
+type pageData struct {
+	Title   string
+	Session *Session
+	Query   string
+	Results []Product
+	// Total is the number of results across all pages; Pages links the
+	// results pages when there is more than one.
+	Total    int
+	Pages    *pagination
+	Filters  *refinements
+	Product  Product
+	Twister  []twisterDimension
+	Cart     []CartLine
+	Subtotal float64
+	Count    int
+	Email    string
+	ReturnTo string
+	Error    string
+}
+
+func NewServer(catalog []Product) *Server {
+	if catalog == nil {
+		catalog = DefaultCatalog
+	}
+
+	s := &Server{
+		catalog:  catalog,
+		sessions: make(map[string]*Session),
+		mux:      http.NewServeMux(),
+	}
+
+	s.tmpl = template.Must(template.New("").Funcs(template.FuncMap{
+		"inr":   formatINR,
+		"stars": func(r float64) string { return strconv.FormatFloat(r, 'f', 1, 64) },
+		"lower": strings.ToLower,
+		// The cart's quantity dropdown, with 0 to delete as on amazon.in.
+		"quantities": func() []int { return []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10} },
+		"discount": func(p Product) int {
+			if p.MRP <= p.Price {
+				return 0
+			}
+			return int((p.MRP - p.Price) / p.MRP * 100)
+		},
+	}).ParseFS(templateFS, "templates/*.html"))
+
+	s.mux.HandleFunc("GET /{$}", s.handleHome)
+	s.mux.HandleFunc("GET /s", s.handleSearch)
+	s.mux.HandleFunc("GET /dp/{asin}", s.handleProduct)
+	s.mux.HandleFunc("POST /cart/add", s.handleAddToCart)
+	s.mux.HandleFunc("GET /cart", s.handleCart)
+	s.mux.HandleFunc("POST /cart/update", s.handleCartUpdate)
+	s.mux.HandleFunc("POST /cart/delete", s.handleCartDelete)
+	s.mux.HandleFunc("GET /checkout", s.handleCheckout)
+	s.mux.HandleFunc("GET /ap/signin", s.handleSignin)
+	s.mux.HandleFunc("POST /ap/signin", s.handleSigninSubmit)
+	s.mux.HandleFunc("GET /checkout/address", s.handleAddress)
+	s.mux.HandleFunc("POST /checkout/address", s.handleAddressSubmit)
+	s.mux.HandleFunc("GET /checkout/payment", s.handlePayment)
+
+	return s
+}
+
+// Start listens on a random localhost port and serves in the background.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	s.listener = listener
+	s.httpServer = &http.Server{Handler: s}
+	go s.httpServer.Serve(listener)
+	return nil
+}
+
+func (s *Server) URL() string {
+	if s.listener == nil {
+		return ""
+	}
+	return "http://" + s.listener.Addr().String()
+}
+
+func (s *Server) Close() error {
+	if s.httpServer != nil {
+		return s.httpServer.Close()
+	}
+	return nil
+}
+
+// Sessions returns a snapshot of every browser session seen so far.
+func (s *Server) Sessions() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		cp := *sess
+		cp.Cart = append([]CartLine(nil), sess.Cart...)
+		cp.Visited = append([]string(nil), sess.Visited...)
+		cp.Address = make(map[string]string, len(sess.Address))
+		for k, v := range sess.Address {
+			cp.Address[k] = v
+		}
+		out = append(out, cp)
+	}
+	return out
+}
+
+type sessionKey struct{}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(w, r)
+	s.mu.Lock()
+	sess.Visited = append(sess.Visited, r.URL.Path)
+	s.mu.Unlock()
+	s.mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, sess)))
+}
+
+func (s *Server) session(w http.ResponseWriter, r *http.Request) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		if sess, ok := s.sessions[c.Value]; ok {
+			return sess
+		}
+	}
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	sess := &Session{ID: hex.EncodeToString(buf), Address: make(map[string]string)}
+	s.sessions[sess.ID] = sess
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: sess.ID, Path: "/"})
+	return sess
+}
+
+func (s *Server) current(r *http.Request) *Session {
+	return r.Context().Value(sessionKey{}).(*Session)
+}
+
+func (s *Server) render(w http.ResponseWriter, r *http.Request, name string, data pageData) {
+	sess := s.current(r)
+	s.mu.Lock()
+	data.Session = sess
+	for _, line := range sess.Cart {
+		data.Count += line.Quantity
+	}
+	err := s.tmpl.ExecuteTemplate(w, name, data)
+	s.mu.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleHome(w http.ResponseWriter, r *http.Request) {
+	s.render(w, r, "home.html", pageData{Title: "Online Shopping site in India: Shop Online for Mobiles, Books, Watches"})
+}
+
+func (s *Server) handleProduct(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.product(r.PathValue("asin"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.render(w, r, "product.html", pageData{Title: p.Title + " : Amazon.in", Product: p, Twister: s.twister(p)})
+}
+
+func (s *Server) handleAddToCart(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	p, ok := s.product(r.FormValue("ASIN"))
+	if !ok {
+		http.Error(w, "unknown ASIN", http.StatusBadRequest)
+		return
+	}
+	if p.OutOfStock {
+		http.Error(w, "currently unavailable", http.StatusConflict)
+		return
+	}
+
+	quantity, err := strconv.Atoi(r.FormValue("quantity"))
+	if err != nil || quantity < 1 {
+		quantity = 1
+	}
+
+	sess := s.current(r)
+	s.mu.Lock()
+	added := false
+	for i := range sess.Cart {
+		if sess.Cart[i].ASIN == p.ASIN {
+			sess.Cart[i].Quantity += quantity
+			added = true
+		}
+	}
+	if !added {
+		sess.Cart = append(sess.Cart, CartLine{ASIN: p.ASIN, Title: p.Title, Price: p.Price, Quantity: quantity})
+	}
+	s.mu.Unlock()
+
+	http.Redirect(w, r, "/cart", http.StatusSeeOther)
+}
+
+func (s *Server) handleCart(w http.ResponseWriter, r *http.Request) {
+	sess := s.current(r)
+	s.mu.Lock()
+	cart := append([]CartLine(nil), sess.Cart...)
+	s.mu.Unlock()
+
+	subtotal := 0.0
+	for _, line := range cart {
+		subtotal += line.Price * float64(line.Quantity)
+	}
+	s.render(w, r, "cart.html", pageData{Title: "Amazon.in Shopping Cart", Cart: cart, Subtotal: subtotal})
+}
+
+// handleCartUpdate sets the quantity of a cart line, removing it at 0.
+func (s *Server) handleCartUpdate(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	quantity, err := strconv.Atoi(r.FormValue("quantity"))
+	if err != nil || quantity < 0 {
+		http.Error(w, "bad quantity", http.StatusBadRequest)
+		return
+	}
+	s.setQuantity(s.current(r), r.FormValue("ASIN"), quantity)
+	http.Redirect(w, r, "/cart", http.StatusSeeOther)
+}
+
+func (s *Server) handleCartDelete(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	s.setQuantity(s.current(r), r.FormValue("ASIN"), 0)
+	http.Redirect(w, r, "/cart", http.StatusSeeOther)
+}
+
+func (s *Server) setQuantity(sess *Session, asin string, quantity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range sess.Cart {
+		if sess.Cart[i].ASIN != asin {
+			continue
+		}
+		if quantity == 0 {
+			sess.Cart = append(sess.Cart[:i], sess.Cart[i+1:]...)
+		} else {
+			sess.Cart[i].Quantity = quantity
+		}
+		return
+	}
+}
+
+func (s *Server) handleCheckout(w http.ResponseWriter, r *http.Request) {
+	sess := s.current(r)
+	s.mu.Lock()
+	signedIn := sess.SignedIn
+	s.mu.Unlock()
+
+	if !signedIn {
+		http.Redirect(w, r, "/ap/signin?return_to=/checkout/address", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/checkout/address", http.StatusSeeOther)
+}
+
+func (s *Server) handleSignin(w http.ResponseWriter, r *http.Request) {
+	s.render(w, r, "signin.html", pageData{
+		Title:    "Amazon Sign In",
+		ReturnTo: returnTo(r),
+	})
+}
+
+// handleSigninSubmit implements Amazon's two-page sign-in: the first post
+// carries only the email, the second the password.
+func (s *Server) handleSigninSubmit(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	email := strings.TrimSpace(r.FormValue("email"))
+	password := r.FormValue("password")
+
+	if email == "" {
+		s.render(w, r, "signin.html", pageData{Title: "Amazon Sign In", ReturnTo: returnTo(r), Error: "Enter your email or mobile phone number"})
+		return
+	}
+	if password == "" {
+		s.render(w, r, "password.html", pageData{Title: "Amazon Sign In", ReturnTo: returnTo(r), Email: email})
+		return
+	}
+
+	sess := s.current(r)
+	s.mu.Lock()
+	sess.Email = email
+	sess.SignedIn = true
+	s.mu.Unlock()
+
+	http.Redirect(w, r, returnTo(r), http.StatusSeeOther)
+}
+
+func (s *Server) handleAddress(w http.ResponseWriter, r *http.Request) {
+	if !s.requireSignin(w, r) {
+		return
+	}
+	s.render(w, r, "address.html", pageData{Title: "Select a delivery address"})
+}
+
+func (s *Server) handleAddressSubmit(w http.ResponseWriter, r *http.Request) {
+	if !s.requireSignin(w, r) {
+		return
+	}
+	r.ParseForm()
+
+	sess := s.current(r)
+	s.mu.Lock()
+	for key, values := range r.PostForm {
+		if strings.HasPrefix(key, "address-ui-widgets-") && len(values) > 0 {
+			sess.Address[strings.TrimPrefix(key, "address-ui-widgets-")] = values[0]
+		}
+	}
+	s.mu.Unlock()
+
+	http.Redirect(w, r, "/checkout/payment", http.StatusSeeOther)
+}
+
+func (s *Server) handlePayment(w http.ResponseWriter, r *http.Request) {
+	if !s.requireSignin(w, r) {
+		return
+	}
+	s.render(w, r, "payment.html", pageData{Title: "Select a Payment Method - Amazon.in Checkout"})
+}
+
+func (s *Server) requireSignin(w http.ResponseWriter, r *http.Request) bool {
+	sess := s.current(r)
+	s.mu.Lock()
+	signedIn := sess.SignedIn
+	s.mu.Unlock()
+
+	if !signedIn {
+		http.Redirect(w, r, "/ap/signin?return_to="+r.URL.Path, http.StatusSeeOther)
+	}
+	return signedIn
+}
+
+func returnTo(r *http.Request) string {
+	target := r.FormValue("return_to")
+	if !strings.HasPrefix(target, "/") {
+		return "/"
+	}
+	return target
+}
+
+// formatINR renders a price the way amazon.in does, e.g. ₹1,299.
+func formatINR(v float64) string {
+	whole := strconv.FormatInt(int64(v), 10)
+	// Indian grouping: last three digits, then groups of two.
+	if len(whole) > 3 {
+		head, tail := whole[:len(whole)-3], whole[len(whole)-3:]
+		var groups []string
+		for len(head) > 2 {
+			groups = append([]string{head[len(head)-2:]}, groups...)
+			head = head[:len(head)-2]
+		}
+		if head != "" {
+			groups = append([]string{head}, groups...)
+		}
+		whole = strings.Join(groups, ",") + "," + tail
+	}
+	return "₹" + whole
+}
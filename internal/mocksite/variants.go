@@ -0,0 +1,77 @@
+package mocksite
+
+// twisterDimension is one variation dimension of a product page, such as
+// its colours, with the variant of the family each option leads to.
+type twisterDimension struct {
+	Name     string
+	Selected string
+	// Swatches shows the options as buttons rather than a dropdown, as
+	// the first dimension is on amazon.in.
+	Swatches bool
+	Options  []twisterOption
+}
+
+type twisterOption struct {
+	Value     string
+	ASIN      string
+	Price     float64
+	Available bool
+	Selected  bool
+}
+
+// twister lists the variation options of p's family. Each option leads to
+// the variant that differs from p only in that dimension; it is available
+// if that variant exists and is in stock.
+func (s *Server) twister(p Product) []twisterDimension {
+	if p.Family == "" {
+		return nil
+	}
+
+	var dims []twisterDimension
+	for i, opt := range p.Variation {
+		dim := twisterDimension{Name: opt.Name, Selected: opt.Value, Swatches: i == 0}
+		seen := make(map[string]bool)
+		for _, sibling := range s.catalog {
+			if sibling.Family != p.Family || len(sibling.Variation) <= i || seen[sibling.Variation[i].Value] {
+				continue
+			}
+			value := sibling.Variation[i].Value
+			seen[value] = true
+
+			target, exact := s.variant(p, i, value)
+			dim.Options = append(dim.Options, twisterOption{
+				Value:     value,
+				ASIN:      target.ASIN,
+				Price:     target.Price,
+				Available: exact && !target.OutOfStock,
+				Selected:  value == opt.Value,
+			})
+		}
+		dims = append(dims, dim)
+	}
+	return dims
+}
+
+// variant finds the variant of p's family with value in dimension i and
+// p's options elsewhere, or else the first with value in dimension i.
+func (s *Server) variant(p Product, i int, value string) (Product, bool) {
+	var fallback Product
+	for _, sibling := range s.catalog {
+		if sibling.Family != p.Family || sibling.Variation[i].Value != value {
+			continue
+		}
+		if fallback.ASIN == "" {
+			fallback = sibling
+		}
+		exact := true
+		for j, opt := range p.Variation {
+			if j != i && sibling.Variation[j] != opt {
+				exact = false
+			}
+		}
+		if exact {
+			return sibling, true
+		}
+	}
+	return fallback, false
+}
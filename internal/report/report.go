@@ -0,0 +1,294 @@
+// Package report renders a self-contained HTML report of an agent run from
+// its run directory: the events.jsonl log, the checkpoint and the
+// screenshots the log links to, which are embedded in the page.
+package report
+
+import (
+	"bufio"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"browser-agent/internal/amazon_agent"
+	"browser-agent/internal/browser"
+)
+
+//go:embed templates/report.html
+var templateFS embed.FS
+
+const eventLogFile = "events.jsonl"
+
+// Report is everything shown in the HTML page, assembled from one run
+// directory.
+type Report struct {
+	RunID       string
+	RunDir      string
+	Task        string
+	Status      string
+	Started     time.Time
+	Finished    time.Time
+	Result      *amazon_agent.ResultEvent
+	Plans       []PlanSection
+	Steps       []StepRow
+	Validations []ValidationRow
+	Recoveries  []amazon_agent.RecoveryAttempt
+	Messages    []MessageRow
+	Memory      *amazon_agent.AgentMemory
+	// Trace and Video are the run's recordings relative to the run
+	// directory, if it has any.
+	Trace       string
+	Video       string
+	GeneratedAt time.Time
+}
+
+// PlanSection is a plan the run executed: the initial one, or one that
+// replaced it after a replan or recovery.
+type PlanSection struct {
+	Kind   string // "initial", "replan", "recovery" or "resumed"
+	Time   time.Time
+	Reason string
+	Error  string
+	Plan   *amazon_agent.Plan
+}
+
+type StepRow struct {
+	Seq       int
+	Time      time.Time
+	Event     amazon_agent.StepEvent
+	Artifacts []ArtifactView
+}
+
+type ArtifactView struct {
+	amazon_agent.Artifact
+	// Image is the screenshot as a data URI, empty if it was not saved or
+	// can no longer be read.
+	Image template.URL
+}
+
+type ValidationRow struct {
+	Time      time.Time
+	AfterStep int
+	amazon_agent.ValidationEvent
+}
+
+type MessageRow struct {
+	Time time.Time
+	Text string
+}
+
+// Load reads runDir's event log and checkpoint.
+func Load(runDir string) (*Report, error) {
+	runDir = filepath.Clean(runDir)
+	f, err := os.Open(filepath.Join(runDir, eventLogFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s has no %s; is it a run directory?", runDir, eventLogFile)
+		}
+		return nil, fmt.Errorf("open run log: %w", err)
+	}
+	defer f.Close()
+
+	r := &Report{RunDir: runDir, RunID: filepath.Base(runDir), GeneratedAt: time.Now()}
+
+	scanner := bufio.NewScanner(f)
+	// Plan events can be long lines.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e amazon_agent.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", eventLogFile, line, err)
+		}
+		r.add(e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read run log: %w", err)
+	}
+
+	if cp, err := amazon_agent.LoadCheckpoint(filepath.Dir(runDir), filepath.Base(runDir)); err == nil {
+		r.Memory = cp.Memory
+		if r.Status == "" {
+			r.Status = cp.Status
+		}
+		if r.Task == "" {
+			r.Task = cp.TaskDescription
+		}
+	}
+	if r.Status == "" {
+		r.Status = amazon_agent.CheckpointRunning
+	}
+	if _, err := os.Stat(filepath.Join(runDir, browser.TraceFile)); err == nil {
+		r.Trace = browser.TraceFile
+	}
+	if _, err := os.Stat(filepath.Join(runDir, browser.VideoFile)); err == nil {
+		r.Video = browser.VideoFile
+	}
+	return r, nil
+}
+
+func (r *Report) add(e amazon_agent.Event) {
+	if r.Started.IsZero() {
+		r.Started = e.Time
+	}
+	r.Finished = e.Time
+	if e.RunID != "" {
+		r.RunID = e.RunID
+	}
+
+	switch e.Type {
+	case amazon_agent.EventTaskStarted:
+		r.Task = e.Task.Description
+		if e.Task.Resumed {
+			r.Plans = append(r.Plans, PlanSection{
+				Kind:   "resumed",
+				Time:   e.Time,
+				Reason: fmt.Sprintf("resumed at step %d of %d", e.Task.StartStep+1, e.Task.TotalSteps),
+			})
+		}
+	case amazon_agent.EventPlanCreated:
+		r.Plans = append(r.Plans, PlanSection{Kind: "initial", Time: e.Time, Plan: e.Plan})
+	case amazon_agent.EventRecovery:
+		rec := e.Recovery
+		if rec == nil {
+			return
+		}
+		r.Recoveries = append(r.Recoveries, *rec)
+		if rec.Plan != nil {
+			r.Plans = append(r.Plans, PlanSection{Kind: string(e.Type), Time: e.Time, Reason: rec.Reason, Plan: rec.Plan})
+		}
+	case amazon_agent.EventReplan:
+		r.Plans = append(r.Plans, PlanSection{
+			Kind:   string(e.Type),
+			Time:   e.Time,
+			Reason: e.Replan.Reason,
+			Error:  e.Replan.Error,
+			Plan:   e.Replan.Plan,
+		})
+	case amazon_agent.EventStepFinished:
+		seq := r.nextSeq()
+		if e.Step.Attempt > 1 && len(r.Steps) > 0 {
+			seq = r.Steps[len(r.Steps)-1].Seq
+		}
+		row := StepRow{Seq: seq, Time: e.Time, Event: *e.Step}
+		for _, a := range e.Step.Artifacts {
+			row.Artifacts = append(row.Artifacts, ArtifactView{Artifact: a, Image: r.image(a.Screenshot)})
+		}
+		r.Steps = append(r.Steps, row)
+	case amazon_agent.EventValidation:
+		r.Validations = append(r.Validations, ValidationRow{Time: e.Time, AfterStep: r.nextSeq() - 1, ValidationEvent: *e.Validation})
+	case amazon_agent.EventTaskDone:
+		r.Result = e.Result
+		switch {
+		case e.Result.Success:
+			r.Status = amazon_agent.CheckpointCompleted
+		case e.Result.Interrupted:
+			r.Status = amazon_agent.CheckpointInterrupted
+		default:
+			r.Status = amazon_agent.CheckpointFailed
+		}
+	case amazon_agent.EventMessage:
+		if text := strings.TrimSpace(e.Message); text != "" {
+			r.Messages = append(r.Messages, MessageRow{Time: e.Time, Text: text})
+		}
+	}
+}
+
+// nextSeq is the sequence number of the next first attempt; retries share
+// the number of the attempt they repeat.
+func (r *Report) nextSeq() int {
+	seq := 1
+	for _, s := range r.Steps {
+		if s.Event.Attempt <= 1 {
+			seq++
+		}
+	}
+	return seq
+}
+
+func (r *Report) image(rel string) template.URL {
+	if rel == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(r.RunDir, filepath.FromSlash(rel)))
+	if err != nil {
+		return ""
+	}
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// Duration is the run's wall-clock span, preferring the agent's own
+// measurement when the run finished.
+func (r *Report) Duration() time.Duration {
+	if r.Result != nil {
+		return r.Result.Duration
+	}
+	return r.Finished.Sub(r.Started)
+}
+
+func (r *Report) Render(w io.Writer) error {
+	tmpl, err := template.New("report.html").Funcs(template.FuncMap{
+		"duration": func(d time.Duration) string {
+			return d.Round(time.Millisecond).String()
+		},
+		"clock": func(t time.Time) string {
+			return t.Local().Format("15:04:05")
+		},
+		"datetime": func(t time.Time) string {
+			return t.Local().Format("2006-01-02 15:04:05")
+		},
+		"percent": func(f float64) string {
+			return fmt.Sprintf("%.0f%%", f*100)
+		},
+		"value": func(s amazon_agent.Step) string {
+			return s.GetValueString()
+		},
+		"inc": func(i int) int {
+			return i + 1
+		},
+		"json": func(v interface{}) string {
+			data, _ := json.MarshalIndent(v, "", "  ")
+			return string(data)
+		},
+		"params": func(p map[string]interface{}) string {
+			if len(p) == 0 {
+				return ""
+			}
+			data, _ := json.Marshal(p)
+			return string(data)
+		},
+	}).ParseFS(templateFS, "templates/report.html")
+	if err != nil {
+		return fmt.Errorf("parse report template: %w", err)
+	}
+	return tmpl.Execute(w, r)
+}
+
+// Write loads runDir and renders its report to outPath.
+func Write(runDir, outPath string) error {
+	r, err := Load(runDir)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+	if err := r.Render(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}